@@ -4,9 +4,9 @@ go 1.23
 
 toolchain go1.23.11
 
-require github.com/lib/pq v1.10.9
-
 require (
-	github.com/gorilla/securecookie v1.1.2 // indirect
-	github.com/gorilla/sessions v1.4.0 // indirect
+	github.com/gorilla/sessions v1.4.0
+	github.com/lib/pq v1.10.9
 )
+
+require github.com/gorilla/securecookie v1.1.2 // indirect
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code-system/models"
+)
+
+// NotifyChannel は品目の変更を他インスタンスへ伝える PostgreSQL の NOTIFY チャネル名
+const NotifyChannel = "item_cache_invalidation"
+
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+	MaxSize       int    `json:"max_size"`
+	TTLSeconds    int64  `json:"ttl_seconds"`
+}
+
+type entry struct {
+	item      models.ItemWithDetails
+	expiresAt time.Time
+}
+
+// ItemCache は品目IDと品目コードで引ける、件数と TTL で上限を設けた LRU キャッシュ。
+// nil の場合は常にミスとして振る舞う。
+type ItemCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ttl     time.Duration
+	lru     *list.List
+	byID    map[string]*list.Element
+	byCode  map[string]string
+	version uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func NewItemCache(maxSize int, ttl time.Duration) *ItemCache {
+	return &ItemCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		lru:     list.New(),
+		byID:    map[string]*list.Element{},
+		byCode:  map[string]string{},
+	}
+}
+
+func (c *ItemCache) GetByID(itemID string) (models.ItemWithDetails, bool) {
+	if c == nil {
+		return models.ItemWithDetails{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(itemID)
+}
+
+func (c *ItemCache) GetByCode(itemCode string) (models.ItemWithDetails, bool) {
+	if c == nil {
+		return models.ItemWithDetails{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	itemID, ok := c.byCode[itemCode]
+	if !ok {
+		c.misses.Add(1)
+		return models.ItemWithDetails{}, false
+	}
+	return c.get(itemID)
+}
+
+func (c *ItemCache) get(itemID string) (models.ItemWithDetails, bool) {
+	el, ok := c.byID[itemID]
+	if !ok {
+		c.misses.Add(1)
+		return models.ItemWithDetails{}, false
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.remove(el)
+		c.misses.Add(1)
+		return models.ItemWithDetails{}, false
+	}
+	c.lru.MoveToFront(el)
+	c.hits.Add(1)
+	return clone(e.item), true
+}
+
+// Version はキャッシュへの読み込み前に取得し、Put に渡す
+func (c *ItemCache) Version() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Put は version 取得後に無効化が発生していなければ品目を格納する。
+// 読み込み中にコミットされた変更で古い内容を書き戻さないためのもの。
+func (c *ItemCache) Put(item models.ItemWithDetails, version uint64) {
+	if c == nil || c.maxSize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version != c.version {
+		return
+	}
+
+	if el, ok := c.byID[item.ItemID]; ok {
+		c.remove(el)
+	}
+
+	el := c.lru.PushFront(&entry{item: clone(item), expiresAt: time.Now().Add(c.ttl)})
+	c.byID[item.ItemID] = el
+	c.byCode[item.ItemCode] = item.ItemID
+
+	for c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *ItemCache) Invalidate(itemID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	c.invalidations.Add(1)
+	if el, ok := c.byID[itemID]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *ItemCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	c.invalidations.Add(1)
+	c.lru.Init()
+	c.byID = map[string]*list.Element{}
+	c.byCode = map[string]string{}
+}
+
+func (c *ItemCache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+	return Stats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+		Size:          size,
+		MaxSize:       c.maxSize,
+		TTLSeconds:    int64(c.ttl / time.Second),
+	}
+}
+
+func (c *ItemCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.byID, e.item.ItemID)
+	if c.byCode[e.item.ItemCode] == e.item.ItemID {
+		delete(c.byCode, e.item.ItemCode)
+	}
+}
+
+func clone(item models.ItemWithDetails) models.ItemWithDetails {
+	if item.ItemNames != nil {
+		names := make(map[string]string, len(item.ItemNames))
+		for k, v := range item.ItemNames {
+			names[k] = v
+		}
+		item.ItemNames = names
+	}
+	if item.TypeA != nil {
+		typeA := *item.TypeA
+		item.TypeA = &typeA
+	}
+	if item.TypeB != nil {
+		typeB := *item.TypeB
+		item.TypeB = &typeB
+	}
+	return item
+}
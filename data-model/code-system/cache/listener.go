@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listen は NotifyChannel を LISTEN し、通知された品目IDのキャッシュを無効化する。
+// 接続が切れた間の通知は失われるため、再接続時にはキャッシュ全体を破棄する。
+func (c *ItemCache) Listen(ctx context.Context, dataSourceName string) error {
+	listener := pq.NewListener(dataSourceName, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("item cache listener: %v", err)
+		}
+		if event == pq.ListenerEventReconnected {
+			c.InvalidateAll()
+		}
+	})
+	if err := listener.Listen(NotifyChannel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					c.InvalidateAll()
+					continue
+				}
+				c.Invalidate(n.Extra)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"code-system/handlers"
+)
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "修復可能な問題を1トランザクションで修復する")
+	asJSON := fs.Bool("json", false, "JSONで出力する")
+	fs.Parse(args)
+
+	report, err := handlers.CheckIntegrity(*repair)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "種別\t品目ID\t内容\t修復")
+		for _, issue := range report.Issues {
+			repairNote := issue.Repair
+			if repairNote == "" {
+				repairNote = "(manual)"
+			} else if issue.Repaired {
+				repairNote = "repaired: " + repairNote
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Type, issue.ItemID, issue.Detail, repairNote)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		types := make([]string, 0, len(report.IssueCounts))
+		for issueType := range report.IssueCounts {
+			types = append(types, issueType)
+		}
+		sort.Strings(types)
+		fmt.Printf("\nchecked %d items, %d issues", report.Checked, len(report.Issues))
+		for _, issueType := range types {
+			fmt.Printf(", %s=%d", issueType, report.IssueCounts[issueType])
+		}
+		fmt.Println()
+		if *repair {
+			fmt.Printf("repaired %d issues\n", report.Repaired)
+		}
+	}
+
+	if len(report.Issues) > report.Repaired {
+		return fmt.Errorf("%d issues remain", len(report.Issues)-report.Repaired)
+	}
+	return nil
+}
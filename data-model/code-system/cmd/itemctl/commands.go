@@ -0,0 +1,455 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"code-system/handlers"
+	"code-system/models"
+)
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	category := fs.String("category", "", "品種区分で絞り込む (A|B)")
+	asJSON := fs.Bool("json", false, "JSONで出力する")
+	fs.Parse(args)
+
+	items, err := handlers.ListItems(*category)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(items)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "品目ID\t品目コード\t品種区分\t品目名\t品種別属性")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.ItemID, item.ItemCode, item.CategoryType, item.ItemName, describeAttributes(item))
+	}
+	return w.Flush()
+}
+
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	code := fs.String("code", "", "品目コードで指定する")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: itemctl show <item_id> | -code <item_code>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	itemID := fs.Arg(0)
+	if (itemID == "") == (*code == "") {
+		fs.Usage()
+		return errors.New("specify either an item ID or -code")
+	}
+
+	item, err := handlers.FindItem(itemID, *code)
+	if err == sql.ErrNoRows {
+		return errors.New("item not found")
+	} else if err != nil {
+		return err
+	}
+	return printJSON(item)
+}
+
+func runCreate(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	req := models.ItemCreateRequest{}
+	fs.StringVar(&req.ItemID, "id", "", "品目ID（必須）")
+	fs.StringVar(&req.ItemName, "name", "", "品目名（必須）")
+	fs.StringVar(&req.CategoryType, "category", "", "品種区分 A|B（必須）")
+	fs.StringVar(&req.ItemCode, "code", "", "品目コード（必須）")
+	attrs := registerAttributeFlags(fs)
+	fs.Parse(args)
+
+	if req.ItemID == "" || req.ItemName == "" || req.CategoryType == "" || req.ItemCode == "" {
+		fs.Usage()
+		return errors.New("-id, -name, -category and -code are required")
+	}
+	req.Capacity, req.Material, req.InnerDiameter, req.OuterDiameter, req.ItemNames = attrs.values(fs)
+
+	err := handlers.WithItemTx(func(tx *sql.Tx) error {
+		return handlers.CreateItemInTx(tx, req)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created %s\n", req.ItemID)
+	return nil
+}
+
+func runUpdate(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	name := fs.String("name", "", "品目名")
+	code := fs.String("code", "", "品目コード")
+	attrs := registerAttributeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: itemctl update [options] <item_id>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	itemID := fs.Arg(0)
+	if itemID == "" {
+		fs.Usage()
+		return errors.New("item ID is required")
+	}
+
+	req := models.ItemUpdateRequest{}
+	set := setFlags(fs)
+	if set["name"] {
+		req.ItemName = name
+	}
+	if set["code"] {
+		req.ItemCode = code
+	}
+	req.Capacity, req.Material, req.InnerDiameter, req.OuterDiameter, req.ItemNames = attrs.values(fs)
+
+	err := handlers.WithItemTx(func(tx *sql.Tx) error {
+		return handlers.UpdateItemInTx(tx, itemID, req)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("updated %s\n", itemID)
+	return nil
+}
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: itemctl delete <item_id>...")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("item ID is required")
+	}
+
+	var attachmentKeys []string
+	err := handlers.WithItemTx(func(tx *sql.Tx) error {
+		for _, itemID := range fs.Args() {
+			keys, err := handlers.DeleteItemInTx(tx, itemID)
+			if err != nil {
+				return fmt.Errorf("%s: %v", itemID, err)
+			}
+			attachmentKeys = append(attachmentKeys, keys...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	handlers.RemoveAttachmentFiles(attachmentKeys)
+
+	fmt.Printf("deleted %s\n", strings.Join(fs.Args(), ", "))
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	file := fs.String("file", "", "出力先（省略時は標準出力）")
+	category := fs.String("category", "", "品種区分で絞り込む (A|B)")
+	fs.Parse(args)
+
+	items, err := handlers.ListItems(*category)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeItemsCSV(w, items); err != nil {
+		return err
+	}
+	if *file != "" {
+		fmt.Fprintf(os.Stderr, "exported %d items to %s\n", len(items), *file)
+	}
+	return nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "", "取り込むCSV（必須）")
+	upsert := fs.Bool("upsert", false, "既存の品目IDは更新する（省略時はエラー）")
+	bestEffort := fs.Bool("best-effort", false, "行ごとにコミットし、失敗した行のみ取り消す（省略時は全行を1トランザクションで登録）")
+	dryRun := fs.Bool("dry-run", false, "検証のみ行い、登録しない")
+	fs.Parse(args)
+
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is required")
+	}
+
+	rows, existing, problems, err := checkCSV(*file, *upsert)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		printProblems(problems)
+		if !*bestEffort {
+			return fmt.Errorf("%d problems found; nothing was imported", len(problems))
+		}
+	}
+	if *dryRun {
+		fmt.Printf("dry run: %d rows would be imported\n", len(rows))
+		return nil
+	}
+
+	apply := func(tx *sql.Tx, row csvRow) error {
+		if existing[row.req.ItemID] {
+			return handlers.UpdateItemInTx(tx, row.req.ItemID, updateRequestFrom(row.req))
+		}
+		return handlers.CreateItemInTx(tx, row.req)
+	}
+
+	if !*bestEffort {
+		err := handlers.WithItemTx(func(tx *sql.Tx) error {
+			for _, row := range rows {
+				if err := apply(tx, row); err != nil {
+					return fmt.Errorf("line %d: %v", row.line, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%v; nothing was imported", err)
+		}
+		fmt.Printf("imported %d rows\n", len(rows))
+		return nil
+	}
+
+	imported := 0
+	for _, row := range rows {
+		err := handlers.WithItemTx(func(tx *sql.Tx) error {
+			return apply(tx, row)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", row.line, err)
+			continue
+		}
+		imported++
+	}
+	fmt.Printf("imported %d of %d rows\n", imported, len(rows)+countLines(problems))
+	if imported < len(rows) || len(problems) > 0 {
+		return errors.New("some rows were not imported")
+	}
+	return nil
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	file := fs.String("file", "", "検証するCSV（必須）")
+	upsert := fs.Bool("upsert", false, "既存の品目IDを更新対象として扱う")
+	fs.Parse(args)
+
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is required")
+	}
+
+	rows, existing, problems, err := checkCSV(*file, *upsert)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		printProblems(problems)
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+
+	updates := 0
+	for _, row := range rows {
+		if existing[row.req.ItemID] {
+			updates++
+		}
+	}
+	fmt.Printf("OK: %d rows (%d new, %d updates)\n", len(rows), len(rows)-updates, updates)
+	return nil
+}
+
+// checkCSV は CSV の形式チェックに加え、DB 上の既存品目との衝突を検出する。
+// 衝突のある行は rows から除き、existing には更新対象となる既存の品目IDを返す。
+func checkCSV(path string, upsert bool) ([]csvRow, map[string]bool, []problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	rows, problems, err := readItemsCSV(f)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	current, err := handlers.ListItems("")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	byID := map[string]models.ItemWithDetails{}
+	codeOwner := map[string]string{}
+	for _, item := range current {
+		byID[item.ItemID] = item
+		codeOwner[item.ItemCode] = item.ItemID
+	}
+
+	existing := map[string]bool{}
+	valid := rows[:0]
+	for _, row := range rows {
+		var rowProblems []string
+		if item, ok := byID[row.req.ItemID]; ok {
+			if !upsert {
+				rowProblems = append(rowProblems, fmt.Sprintf("item_id %s already exists (use -upsert to update)", row.req.ItemID))
+			} else if item.CategoryType != row.req.CategoryType {
+				rowProblems = append(rowProblems, fmt.Sprintf("category_type of %s cannot be changed from %s to %s", row.req.ItemID, item.CategoryType, row.req.CategoryType))
+			}
+		}
+		if owner, ok := codeOwner[row.req.ItemCode]; ok && owner != row.req.ItemID {
+			rowProblems = append(rowProblems, fmt.Sprintf("item_code %s is already used by %s", row.req.ItemCode, owner))
+		}
+
+		if len(rowProblems) > 0 {
+			for _, m := range rowProblems {
+				problems = append(problems, problem{line: row.line, message: m})
+			}
+			continue
+		}
+		if _, ok := byID[row.req.ItemID]; ok {
+			existing[row.req.ItemID] = true
+		}
+		valid = append(valid, row)
+	}
+
+	return valid, existing, problems, nil
+}
+
+func updateRequestFrom(req models.ItemCreateRequest) models.ItemUpdateRequest {
+	return models.ItemUpdateRequest{
+		ItemName:      &req.ItemName,
+		ItemCode:      &req.ItemCode,
+		ItemNames:     req.ItemNames,
+		Capacity:      req.Capacity,
+		Material:      req.Material,
+		InnerDiameter: req.InnerDiameter,
+		OuterDiameter: req.OuterDiameter,
+	}
+}
+
+type attributeFlags struct {
+	capacity      *float64
+	material      *string
+	innerDiameter *float64
+	outerDiameter *float64
+	nameEN        *string
+	nameZH        *string
+}
+
+func registerAttributeFlags(fs *flag.FlagSet) *attributeFlags {
+	return &attributeFlags{
+		capacity:      fs.Float64("capacity", 0, "容量（A品種）"),
+		material:      fs.String("material", "", "材質（A品種）"),
+		innerDiameter: fs.Float64("inner-diameter", 0, "内径（B品種）"),
+		outerDiameter: fs.Float64("outer-diameter", 0, "外径（B品種）"),
+		nameEN:        fs.String("name-en", "", "品目名（英語）。update で空文字を指定すると削除する"),
+		nameZH:        fs.String("name-zh", "", "品目名（中国語）。update で空文字を指定すると削除する"),
+	}
+}
+
+// values は明示的に指定されたフラグのみを値として返す
+func (a *attributeFlags) values(fs *flag.FlagSet) (capacity *float64, material *string, innerDiameter *float64, outerDiameter *float64, names map[string]string) {
+	set := setFlags(fs)
+	if set["capacity"] {
+		capacity = a.capacity
+	}
+	if set["material"] {
+		material = a.material
+	}
+	if set["inner-diameter"] {
+		innerDiameter = a.innerDiameter
+	}
+	if set["outer-diameter"] {
+		outerDiameter = a.outerDiameter
+	}
+	if set["name-en"] || set["name-zh"] {
+		names = map[string]string{}
+		if set["name-en"] {
+			names["en"] = *a.nameEN
+		}
+		if set["name-zh"] {
+			names["zh"] = *a.nameZH
+		}
+	}
+	return
+}
+
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+func describeAttributes(item models.ItemWithDetails) string {
+	var parts []string
+	if item.TypeA != nil {
+		if item.TypeA.Capacity.Valid {
+			parts = append(parts, fmt.Sprintf("容量=%g", item.TypeA.Capacity.Float64))
+		}
+		if item.TypeA.Material.Valid {
+			parts = append(parts, "材質="+item.TypeA.Material.String)
+		}
+	}
+	if item.TypeB != nil {
+		if item.TypeB.InnerDiameter.Valid {
+			parts = append(parts, fmt.Sprintf("内径=%g", item.TypeB.InnerDiameter.Float64))
+		}
+		if item.TypeB.OuterDiameter.Valid {
+			parts = append(parts, fmt.Sprintf("外径=%g", item.TypeB.OuterDiameter.Float64))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printProblems(problems []problem) {
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+}
+
+func countLines(problems []problem) int {
+	lines := map[int]bool{}
+	for _, p := range problems {
+		lines[p.line] = true
+	}
+	return len(lines)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"code-system/models"
+)
+
+var csvHeader = []string{
+	"item_id", "item_name", "category_type", "item_code",
+	"capacity", "material", "inner_diameter", "outer_diameter",
+	"item_name_en", "item_name_zh",
+}
+
+type csvRow struct {
+	line int
+	req  models.ItemCreateRequest
+}
+
+type problem struct {
+	line    int
+	message string
+}
+
+func (p problem) String() string {
+	if p.line == 0 {
+		return p.message
+	}
+	return fmt.Sprintf("line %d: %s", p.line, p.message)
+}
+
+// readItemsCSV は CSV を読み込み、行単位の形式チェックを行う。問題のある行は rows に含めない
+func readItemsCSV(r io.Reader) ([]csvRow, []problem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("CSV is empty")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+	for _, name := range csvHeader[:4] {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("CSV header must include %s", name)
+		}
+	}
+
+	var rows []csvRow
+	var problems []problem
+	seenIDs := map[string]int{}
+	seenCodes := map[string]int{}
+
+	for i, record := range records[1:] {
+		line := i + 2
+		get := func(name string) string {
+			idx, ok := columns[name]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		req := models.ItemCreateRequest{
+			ItemID:       get("item_id"),
+			ItemName:     get("item_name"),
+			CategoryType: get("category_type"),
+			ItemCode:     get("item_code"),
+		}
+
+		var rowProblems []string
+		require := func(name, value string, max int) {
+			if value == "" {
+				rowProblems = append(rowProblems, name+" is required")
+			} else if utf8.RuneCountInString(value) > max {
+				rowProblems = append(rowProblems, fmt.Sprintf("%s must be at most %d characters", name, max))
+			}
+		}
+		require("item_id", req.ItemID, 10)
+		require("item_name", req.ItemName, 100)
+		require("item_code", req.ItemCode, 20)
+		if req.CategoryType != "A" && req.CategoryType != "B" {
+			rowProblems = append(rowProblems, "category_type must be 'A' or 'B'")
+		}
+
+		number := func(name string) *float64 {
+			v := get(name)
+			if v == "" {
+				return nil
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				rowProblems = append(rowProblems, name+" must be a number")
+				return nil
+			}
+			return &f
+		}
+		req.Capacity = number("capacity")
+		req.InnerDiameter = number("inner_diameter")
+		req.OuterDiameter = number("outer_diameter")
+		if v := get("material"); v != "" {
+			if utf8.RuneCountInString(v) > 50 {
+				rowProblems = append(rowProblems, "material must be at most 50 characters")
+			}
+			req.Material = &v
+		}
+
+		if req.CategoryType == "A" && (req.InnerDiameter != nil || req.OuterDiameter != nil) {
+			rowProblems = append(rowProblems, "inner_diameter and outer_diameter are only for category B")
+		}
+		if req.CategoryType == "B" && (req.Capacity != nil || req.Material != nil) {
+			rowProblems = append(rowProblems, "capacity and material are only for category A")
+		}
+
+		for _, locale := range models.SupportedLocales {
+			if locale == models.DefaultLocale {
+				continue
+			}
+			if v := get("item_name_" + locale); v != "" {
+				if utf8.RuneCountInString(v) > 100 {
+					rowProblems = append(rowProblems, fmt.Sprintf("item_name_%s must be at most 100 characters", locale))
+				}
+				if req.ItemNames == nil {
+					req.ItemNames = map[string]string{}
+				}
+				req.ItemNames[locale] = v
+			}
+		}
+
+		if prev, ok := seenIDs[req.ItemID]; ok && req.ItemID != "" {
+			rowProblems = append(rowProblems, fmt.Sprintf("item_id %s is duplicated (line %d)", req.ItemID, prev))
+		}
+		if prev, ok := seenCodes[req.ItemCode]; ok && req.ItemCode != "" {
+			rowProblems = append(rowProblems, fmt.Sprintf("item_code %s is duplicated (line %d)", req.ItemCode, prev))
+		}
+		seenIDs[req.ItemID] = line
+		seenCodes[req.ItemCode] = line
+
+		if len(rowProblems) > 0 {
+			for _, m := range rowProblems {
+				problems = append(problems, problem{line: line, message: m})
+			}
+			continue
+		}
+		rows = append(rows, csvRow{line: line, req: req})
+	}
+
+	return rows, problems, nil
+}
+
+func writeItemsCSV(w io.Writer, items []models.ItemWithDetails) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		record := make([]string, len(csvHeader))
+		record[0] = item.ItemID
+		record[1] = item.ItemNames[models.DefaultLocale]
+		record[2] = item.CategoryType
+		record[3] = item.ItemCode
+		if item.TypeA != nil {
+			if item.TypeA.Capacity.Valid {
+				record[4] = strconv.FormatFloat(item.TypeA.Capacity.Float64, 'f', -1, 64)
+			}
+			if item.TypeA.Material.Valid {
+				record[5] = item.TypeA.Material.String
+			}
+		}
+		if item.TypeB != nil {
+			if item.TypeB.InnerDiameter.Valid {
+				record[6] = strconv.FormatFloat(item.TypeB.InnerDiameter.Float64, 'f', -1, 64)
+			}
+			if item.TypeB.OuterDiameter.Valid {
+				record[7] = strconv.FormatFloat(item.TypeB.OuterDiameter.Float64, 'f', -1, 64)
+			}
+		}
+		record[8] = item.ItemNames["en"]
+		record[9] = item.ItemNames["zh"]
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
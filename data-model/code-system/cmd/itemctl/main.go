@@ -0,0 +1,77 @@
+// itemctl は品目マスタを DB から直接操作する管理者向けコマンドラインツール。
+// メンテナンス時間帯やスクリプトからの一括操作を想定している。
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"code-system/handlers"
+	"code-system/storage"
+)
+
+const usage = `Usage: itemctl <command> [options]
+
+Commands:
+  list      品目一覧を表示する
+  show      品目の詳細を表示する
+  create    品目を作成する
+  update    品目を更新する
+  delete    品目を削除する
+  export    品目をCSVに出力する
+  import    CSVから品目を登録する
+  validate  CSVを検証する（DBへの書き込みは行わない）
+  check     品目基本属性と品種別属性の整合性を検査する
+
+DB connection uses DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME.
+Set ATTACHMENT_DIR to remove attachment files when deleting items.
+Run "itemctl <command> -h" for command options.
+`
+
+var commands = map[string]func(args []string) error{
+	"list":     runList,
+	"show":     runShow,
+	"create":   runCreate,
+	"update":   runUpdate,
+	"delete":   runDelete,
+	"export":   runExport,
+	"import":   runImport,
+	"validate": runValidate,
+	"check":    runCheck,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	handlers.SetDB(db)
+
+	if dir := os.Getenv("ATTACHMENT_DIR"); dir != "" {
+		attachmentStorage, err := storage.NewLocalStorage(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		handlers.SetStorage(attachmentStorage)
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
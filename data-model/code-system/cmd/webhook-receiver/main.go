@@ -0,0 +1,46 @@
+// webhook-receiver は品目イベントの Webhook 配信を手元で確認するための受信サーバー
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+
+	"code-system/events"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String("secret", "", "webhook secret used to verify signatures")
+	fail := flag.Bool("fail", false, "respond with 500 to exercise retries and dead letters")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		timestamp := r.Header.Get(events.TimestampHeader)
+		signature := r.Header.Get(events.SignatureHeader)
+		verified := *secret != "" && events.Verify(*secret, timestamp, body, signature)
+
+		log.Printf("event=%s type=%s verified=%t body=%s",
+			r.Header.Get(events.EventIDHeader), r.Header.Get(events.EventTypeHeader), verified, body)
+
+		if *secret != "" && !verified {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if *fail {
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
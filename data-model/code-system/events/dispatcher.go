@@ -0,0 +1,245 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-system/models"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventIDHeader   = "X-Webhook-Event-ID"
+	EventTypeHeader = "X-Webhook-Event-Type"
+)
+
+type DispatcherConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+}
+
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		Interval:    2 * time.Second,
+		BatchSize:   20,
+		MaxAttempts: 8,
+		BaseBackoff: 5 * time.Second,
+		MaxBackoff:  30 * time.Minute,
+		Timeout:     10 * time.Second,
+	}
+}
+
+type Dispatcher struct {
+	db     *sql.DB
+	client *http.Client
+	config DispatcherConfig
+}
+
+func NewDispatcher(db *sql.DB, config DispatcherConfig) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: config.Timeout},
+		config: config,
+	}
+}
+
+// Run は ctx がキャンセルされるまで保留中の配信を定期的に送信する
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.DispatchPending(ctx)
+			if err != nil {
+				log.Printf("webhook dispatch failed: %v", err)
+				break
+			}
+			if n < d.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type pendingDelivery struct {
+	deliveryID int64
+	attempts   int
+	url        string
+	secret     string
+	event      models.ItemEvent
+}
+
+// DispatchPending は送信期限の来た配信を1バッチ分送信し、処理件数を返す
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	// バッチ内の配信は順に送信するため、リース期間は全件がタイムアウトした場合の所要時間に余裕を加えたものとする
+	lease := time.Duration(d.config.BatchSize)*d.config.Timeout + d.config.Interval
+
+	// 他インスタンスと重複しないよう、次回試行日時をリース期間だけ先送りして確保する
+	rows, err := d.db.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE Webhook配信
+			SET 次回試行日時 = CURRENT_TIMESTAMP + $2::float8 * INTERVAL '1 millisecond'
+			WHERE 配信ID IN (
+				SELECT 配信ID FROM Webhook配信
+				WHERE 状態 = 'pending' AND 次回試行日時 <= CURRENT_TIMESTAMP
+				ORDER BY 配信ID
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING 配信ID, イベントID, WebhookID, 試行回数
+		)
+		SELECT c.配信ID, c.試行回数, w.URL, w.シークレット,
+			   e.イベントID, e.イベント種別, e.品目ID, e.ペイロード, e.発生日時
+		FROM claimed c
+		JOIN Webhook w ON c.WebhookID = w.WebhookID
+		JOIN 品目イベント e ON c.イベントID = e.イベントID
+		ORDER BY c.配信ID`,
+		d.config.BatchSize, lease.Milliseconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var deliveries []pendingDelivery
+	for rows.Next() {
+		var p pendingDelivery
+		err := rows.Scan(&p.deliveryID, &p.attempts, &p.url, &p.secret,
+			&p.event.EventID, &p.event.EventType, &p.event.ItemID, &p.event.Payload, &p.event.OccurredAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		deliveries = append(deliveries, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range deliveries {
+		statusCode, sendErr := d.send(ctx, p)
+		if err := d.recordResult(p, statusCode, sendErr); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, p pendingDelivery) (int, error) {
+	body, err := json.Marshal(p.event)
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, strconv.FormatInt(p.event.EventID, 10))
+	req.Header.Set(EventTypeHeader, p.event.EventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(p.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) recordResult(p pendingDelivery, statusCode int, sendErr error) error {
+	var code interface{}
+	if statusCode != 0 {
+		code = statusCode
+	}
+
+	if sendErr == nil {
+		_, err := d.db.Exec(`
+			UPDATE Webhook配信
+			SET 状態 = 'delivered', 試行回数 = 試行回数 + 1, 最終ステータスコード = $2,
+				最終エラー = NULL, 配信日時 = CURRENT_TIMESTAMP
+			WHERE 配信ID = $1`,
+			p.deliveryID, code,
+		)
+		return err
+	}
+
+	attempts := p.attempts + 1
+	status := DeliveryPending
+	if attempts >= d.config.MaxAttempts {
+		status = DeliveryDead
+	}
+
+	_, err := d.db.Exec(`
+		UPDATE Webhook配信
+		SET 状態 = $2, 試行回数 = $3, 最終ステータスコード = $4, 最終エラー = $5,
+			次回試行日時 = CURRENT_TIMESTAMP + $6::float8 * INTERVAL '1 millisecond'
+		WHERE 配信ID = $1`,
+		p.deliveryID, status, attempts, code, sendErr.Error(), d.backoff(attempts).Milliseconds(),
+	)
+	return err
+}
+
+// backoff は試行回数に応じて BaseBackoff から倍々に伸ばした待機時間を返す
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.config.MaxBackoff {
+			return d.config.MaxBackoff
+		}
+	}
+	return wait
+}
+
+// Sign は "タイムスタンプ.本文" に対する HMAC-SHA256 署名を16進文字列で返す
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify は受信側で署名ヘッダ（"sha256=..."）を検証する
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
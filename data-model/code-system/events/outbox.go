@@ -0,0 +1,31 @@
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// Record は品目の変更イベントを呼び出し元のトランザクション内で書き込み、
+// 有効な全 Webhook への配信行を作成する
+func Record(tx *sql.Tx, eventType, itemID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var eventID int64
+	err = tx.QueryRow(
+		"INSERT INTO 品目イベント (イベント種別, 品目ID, ペイロード) VALUES ($1, $2, $3) RETURNING イベントID",
+		eventType, itemID, body,
+	).Scan(&eventID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO Webhook配信 (イベントID, WebhookID)
+		SELECT $1, WebhookID FROM Webhook WHERE 有効 = TRUE`,
+		eventID,
+	)
+	return err
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"code-system/models"
+	"code-system/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxAttachmentSize = 20 << 20
+	// maxUploadBodySize ファイル以外のフィールドとマルチパートの区切りの分を見込んだ、アップロードの本文の上限
+	maxUploadBodySize = maxAttachmentSize + 1<<20
+	// maxUploadMemory これを超えるファイルはメモリではなく一時ファイルに保持する
+	maxUploadMemory = 8 << 20
+)
+
+// storageKeyExt 保存キーに残す拡張子（英数字10文字まで。それ以外の拡張子は保存キーに含めない）
+var storageKeyExt = regexp.MustCompile(`^\.[A-Za-z0-9]{1,10}$`)
+
+var attachmentTypes = map[string]bool{
+	"drawing":    true,
+	"spec_sheet": true,
+	"image":      true,
+	"other":      true,
+}
+
+var Storage storage.Storage
+
+func SetStorage(s storage.Storage) {
+	Storage = s
+}
+
+func UploadAttachment(c echo.Context) error {
+	itemID := c.Param("id")
+
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS (SELECT 1 FROM 品目基本属性 WHERE 品目ID = $1)", itemID).Scan(&exists)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch item",
+		})
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Item not found",
+		})
+	}
+
+	// 上限を超える本文はフォームの解析中に読み込みを打ち切る
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxUploadBodySize)
+	if err := req.ParseMultipartForm(maxUploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, models.Response{
+				Success: false,
+				Error:   "File exceeds the maximum size of 20MB",
+			})
+		}
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid multipart form",
+		})
+	}
+
+	attachmentType := c.FormValue("attachment_type")
+	if attachmentType == "" {
+		attachmentType = "other"
+	}
+	if !attachmentTypes[attachmentType] {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Attachment type must be one of 'drawing', 'spec_sheet', 'image', 'other'",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "File is required",
+		})
+	}
+	if fileHeader.Size > maxAttachmentSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, models.Response{
+			Success: false,
+			Error:   "File exceeds the maximum size of 20MB",
+		})
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Failed to read uploaded file",
+		})
+	}
+	defer src.Close()
+
+	key, err := newStorageKey(itemID, fileHeader.Filename)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to allocate storage key",
+		})
+	}
+
+	hash := sha256.New()
+	size, err := Storage.Save(key, io.TeeReader(src, hash))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to store file",
+		})
+	}
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(fileHeader.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	attachment := models.Attachment{
+		ItemID:         itemID,
+		FileName:       filepath.Base(fileHeader.Filename),
+		AttachmentType: attachmentType,
+		ContentType:    contentType,
+		Size:           size,
+		Checksum:       hex.EncodeToString(hash.Sum(nil)),
+		UploadedBy:     c.FormValue("uploaded_by"),
+		StorageKey:     key,
+	}
+
+	err = DB.QueryRow(`
+		INSERT INTO 品目添付ファイル (品目ID, ファイル名, ファイル種別, コンテンツタイプ, ファイルサイズ, チェックサム, 登録者, 保存キー)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING 添付ファイルID, 登録日時`,
+		attachment.ItemID, attachment.FileName, attachment.AttachmentType, attachment.ContentType,
+		attachment.Size, attachment.Checksum, attachment.UploadedBy, attachment.StorageKey,
+	).Scan(&attachment.AttachmentID, &attachment.UploadedAt)
+	if err != nil {
+		Storage.Delete(key)
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to save attachment metadata",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.Response{
+		Success: true,
+		Data:    attachment,
+	})
+}
+
+func GetAttachments(c echo.Context) error {
+	itemID := c.Param("id")
+
+	rows, err := DB.Query(`
+		SELECT 添付ファイルID, 品目ID, ファイル名, ファイル種別, コンテンツタイプ,
+			   ファイルサイズ, チェックサム, 登録者, 保存キー, 登録日時
+		FROM 品目添付ファイル
+		WHERE 品目ID = $1
+		ORDER BY 登録日時 DESC, 添付ファイルID DESC`, itemID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch attachments",
+		})
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var a models.Attachment
+		err := rows.Scan(
+			&a.AttachmentID, &a.ItemID, &a.FileName, &a.AttachmentType, &a.ContentType,
+			&a.Size, &a.Checksum, &a.UploadedBy, &a.StorageKey, &a.UploadedAt,
+		)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to fetch attachments",
+			})
+		}
+		attachments = append(attachments, a)
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    attachments,
+	})
+}
+
+func DownloadAttachment(c echo.Context) error {
+	attachment, err := findAttachment(c)
+	if err != nil {
+		return err
+	}
+	if attachment == nil {
+		return nil
+	}
+
+	f, err := Storage.Open(attachment.StorageKey)
+	if err == storage.ErrNotFound {
+		return c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Attachment file is missing from storage",
+		})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to open attachment",
+		})
+	}
+	defer f.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	c.Response().Header().Set("X-Checksum-SHA256", attachment.Checksum)
+	return c.Stream(http.StatusOK, attachment.ContentType, f)
+}
+
+func DeleteAttachment(c echo.Context) error {
+	attachment, err := findAttachment(c)
+	if err != nil {
+		return err
+	}
+	if attachment == nil {
+		return nil
+	}
+
+	_, err = DB.Exec("DELETE FROM 品目添付ファイル WHERE 添付ファイルID = $1", attachment.AttachmentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to delete attachment",
+		})
+	}
+
+	removeAttachmentFiles([]string{attachment.StorageKey})
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: "Attachment deleted successfully",
+	})
+}
+
+// findAttachment は見つからない場合にレスポンスを書き込み nil を返す
+func findAttachment(c echo.Context) (*models.Attachment, error) {
+	itemID := c.Param("id")
+	attachmentID, err := strconv.ParseInt(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		return nil, c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid attachment ID",
+		})
+	}
+
+	var a models.Attachment
+	err = DB.QueryRow(`
+		SELECT 添付ファイルID, 品目ID, ファイル名, ファイル種別, コンテンツタイプ,
+			   ファイルサイズ, チェックサム, 登録者, 保存キー, 登録日時
+		FROM 品目添付ファイル
+		WHERE 添付ファイルID = $1 AND 品目ID = $2`, attachmentID, itemID).Scan(
+		&a.AttachmentID, &a.ItemID, &a.FileName, &a.AttachmentType, &a.ContentType,
+		&a.Size, &a.Checksum, &a.UploadedBy, &a.StorageKey, &a.UploadedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Attachment not found",
+		})
+	} else if err != nil {
+		return nil, c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch attachment",
+		})
+	}
+
+	return &a, nil
+}
+
+func attachmentStorageKeys(tx *sql.Tx, itemID string) ([]string, error) {
+	rows, err := tx.Query("SELECT 保存キー FROM 品目添付ファイル WHERE 品目ID = $1", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func newStorageKey(itemID, fileName string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// 拡張子はクライアントが指定したファイル名のものなので、保存キーの列の長さを超えないよう制限する
+	ext := filepath.Ext(filepath.Base(fileName))
+	if !storageKeyExt.MatchString(ext) {
+		ext = ""
+	}
+	return hex.EncodeToString([]byte(itemID)) + "/" + hex.EncodeToString(b) + ext, nil
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxBatchSize = 100
+
+func BatchGetItems(c echo.Context) error {
+	var req models.ItemBatchGetRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if len(req.ItemIDs)+len(req.ItemCodes) == 0 {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "item_ids or item_codes is required",
+		})
+	}
+	if len(req.ItemIDs)+len(req.ItemCodes) > maxBatchSize {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Too many items requested (max 100)",
+		})
+	}
+
+	byID, byCode, err := lookupItems(req.ItemIDs, req.ItemCodes)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch items",
+		})
+	}
+	locale := resolveLocale(c)
+
+	// リクエストの順序で返し、同じ品目を重複して含めない
+	resp := models.ItemBatchGetResponse{
+		Items:             []models.ItemWithDetails{},
+		NotFoundItemIDs:   []string{},
+		NotFoundItemCodes: []string{},
+	}
+	seen := map[string]bool{}
+	for _, id := range req.ItemIDs {
+		item, ok := byID[id]
+		if !ok {
+			resp.NotFoundItemIDs = append(resp.NotFoundItemIDs, id)
+			continue
+		}
+		if !seen[item.ItemID] {
+			seen[item.ItemID] = true
+			resp.Items = append(resp.Items, localizeItem(item, locale, false))
+		}
+	}
+	for _, code := range req.ItemCodes {
+		item, ok := byCode[code]
+		if !ok {
+			resp.NotFoundItemCodes = append(resp.NotFoundItemCodes, code)
+			continue
+		}
+		if !seen[item.ItemID] {
+			seen[item.ItemID] = true
+			resp.Items = append(resp.Items, localizeItem(item, locale, false))
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    resp,
+	})
+}
+
+// BatchWriteItems は作成・更新・削除の混在した操作を実行する。
+// atomic モードでは全操作を1トランザクションで実行し、1件でも失敗すれば全てロールバックする。
+// best_effort モードでは操作ごとにトランザクションを分け、失敗した操作のみを取り消す。
+func BatchWriteItems(c echo.Context) error {
+	var req models.ItemBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	if req.Mode == "" {
+		req.Mode = models.BatchModeAtomic
+	}
+	if req.Mode != models.BatchModeAtomic && req.Mode != models.BatchModeBestEffort {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Mode must be 'atomic' or 'best_effort'",
+		})
+	}
+	if len(req.Operations) == 0 {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "operations is required",
+		})
+	}
+	if len(req.Operations) > maxBatchSize {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Too many operations (max 100)",
+		})
+	}
+
+	resp := models.ItemBatchResponse{
+		Mode:    req.Mode,
+		Results: make([]models.ItemBatchResult, len(req.Operations)),
+	}
+
+	if req.Mode == models.BatchModeAtomic {
+		if err := runAtomicBatch(req.Operations, &resp); err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+		}
+	} else {
+		runBestEffortBatch(req.Operations, &resp)
+	}
+
+	for _, r := range resp.Results {
+		if r.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: resp.Failed == 0,
+		Data:    resp,
+	})
+}
+
+func runAtomicBatch(ops []models.ItemBatchOperation, resp *models.ItemBatchResponse) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to start transaction"}
+	}
+	defer tx.Rollback()
+
+	var attachmentKeys []string
+	failed := false
+	for i, op := range ops {
+		if failed {
+			resp.Results[i] = models.ItemBatchResult{
+				Index:  i,
+				Op:     op.Op,
+				ItemID: op.ItemID,
+				Status: http.StatusFailedDependency,
+				Error:  "Not executed because a previous operation failed",
+			}
+			continue
+		}
+
+		keys, result := applyBatchOperation(tx, i, op)
+		resp.Results[i] = result
+		if !result.Success {
+			failed = true
+			continue
+		}
+		attachmentKeys = append(attachmentKeys, keys...)
+	}
+
+	if failed {
+		for i := range resp.Results {
+			if resp.Results[i].Success {
+				resp.Results[i].Success = false
+				resp.Results[i].Status = http.StatusFailedDependency
+				resp.Results[i].Error = "Rolled back because another operation failed"
+				resp.Results[i].Item = nil
+			}
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to commit transaction"}
+	}
+	resp.Committed = true
+	for _, r := range resp.Results {
+		ItemCache.Invalidate(r.ItemID)
+	}
+	removeAttachmentFiles(attachmentKeys)
+	return nil
+}
+
+func runBestEffortBatch(ops []models.ItemBatchOperation, resp *models.ItemBatchResponse) {
+	for i, op := range ops {
+		tx, err := DB.Begin()
+		if err != nil {
+			resp.Results[i] = batchFailure(i, op, &itemError{http.StatusInternalServerError, "Failed to start transaction"})
+			continue
+		}
+
+		keys, result := applyBatchOperation(tx, i, op)
+		if !result.Success {
+			tx.Rollback()
+			resp.Results[i] = result
+			continue
+		}
+
+		if err := tx.Commit(); err != nil {
+			resp.Results[i] = batchFailure(i, op, &itemError{http.StatusInternalServerError, "Failed to commit transaction"})
+			continue
+		}
+		resp.Committed = true
+		resp.Results[i] = result
+		ItemCache.Invalidate(result.ItemID)
+		removeAttachmentFiles(keys)
+	}
+}
+
+func applyBatchOperation(tx *sql.Tx, index int, op models.ItemBatchOperation) ([]string, models.ItemBatchResult) {
+	switch op.Op {
+	case models.BatchOpCreate:
+		var req models.ItemCreateRequest
+		if err := json.Unmarshal(op.Data, &req); err != nil {
+			return nil, batchFailure(index, op, &itemError{http.StatusBadRequest, "Invalid operation data"})
+		}
+		op.ItemID = req.ItemID
+		if ierr := createItemTx(tx, req); ierr != nil {
+			return nil, batchFailure(index, op, ierr)
+		}
+		return nil, batchSuccess(tx, index, op, http.StatusCreated)
+
+	case models.BatchOpUpdate:
+		var req models.ItemUpdateRequest
+		if err := json.Unmarshal(op.Data, &req); err != nil {
+			return nil, batchFailure(index, op, &itemError{http.StatusBadRequest, "Invalid operation data"})
+		}
+		if op.ItemID == "" {
+			return nil, batchFailure(index, op, &itemError{http.StatusBadRequest, "item_id is required"})
+		}
+		if ierr := updateItemTx(tx, op.ItemID, req); ierr != nil {
+			return nil, batchFailure(index, op, ierr)
+		}
+		return nil, batchSuccess(tx, index, op, http.StatusOK)
+
+	case models.BatchOpDelete:
+		if op.ItemID == "" {
+			return nil, batchFailure(index, op, &itemError{http.StatusBadRequest, "item_id is required"})
+		}
+		keys, ierr := deleteItemTx(tx, op.ItemID)
+		if ierr != nil {
+			return nil, batchFailure(index, op, ierr)
+		}
+		return keys, models.ItemBatchResult{
+			Index:   index,
+			Op:      op.Op,
+			ItemID:  op.ItemID,
+			Success: true,
+			Status:  http.StatusOK,
+		}
+	}
+
+	return nil, batchFailure(index, op, &itemError{http.StatusBadRequest, "Op must be 'create', 'update' or 'delete'"})
+}
+
+func batchSuccess(tx *sql.Tx, index int, op models.ItemBatchOperation, status int) models.ItemBatchResult {
+	item, err := fetchItemSnapshot(tx, op.ItemID)
+	if err != nil {
+		item = nil
+	}
+	return models.ItemBatchResult{
+		Index:   index,
+		Op:      op.Op,
+		ItemID:  op.ItemID,
+		Success: true,
+		Status:  status,
+		Item:    item,
+	}
+}
+
+func batchFailure(index int, op models.ItemBatchOperation, ierr *itemError) models.ItemBatchResult {
+	return models.ItemBatchResult{
+		Index:  index,
+		Op:     op.Op,
+		ItemID: op.ItemID,
+		Status: ierr.status,
+		Error:  ierr.message,
+	}
+}
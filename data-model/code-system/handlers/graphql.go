@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code-system/models"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+type localeContextKey struct{}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+var itemSchema graphql.Schema
+
+func init() {
+	var err error
+	itemSchema, err = newItemSchema()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// GraphQL は GraphQL のクエリを実行する。レスポンスは GraphQL の仕様どおり {data, errors} 形式で返す
+func GraphQL(c echo.Context) error {
+	var req graphQLRequest
+	if c.Request().Method == http.MethodGet {
+		req.Query = c.QueryParam("query")
+		req.OperationName = c.QueryParam("operationName")
+		if v := c.QueryParam("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]interface{}{
+					"errors": []map[string]string{{"message": "Invalid variables"}},
+				})
+			}
+		}
+	} else if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"errors": []map[string]string{{"message": "Invalid request body"}},
+		})
+	}
+
+	// GET はキャッシュやリンクのプリフェッチで実行されうるため、書き込みは POST に限る
+	if c.Request().Method == http.MethodGet && isMutation(req.Query, req.OperationName) {
+		c.Response().Header().Set("Allow", http.MethodPost)
+		return c.JSON(http.StatusMethodNotAllowed, map[string]interface{}{
+			"errors": []map[string]string{{"message": "Mutations must be sent with POST"}},
+		})
+	}
+
+	ctx := context.WithValue(c.Request().Context(), localeContextKey{}, resolveLocale(c))
+	result := graphql.Do(graphql.Params{
+		Schema:         itemSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// isMutation は実行される操作がミューテーションかどうかを返す
+// 構文エラーの場合は false を返し、エラーの報告は graphql.Do に任せる
+func isMutation(query, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.Operation != ast.OperationTypeMutation {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return true
+		}
+	}
+	return false
+}
+
+func newItemSchema() (graphql.Schema, error) {
+	localizedName := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LocalizedName",
+		Fields: graphql.Fields{
+			"locale": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	commonFields := func() graphql.Fields {
+		return graphql.Fields{
+			"itemId":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: itemField(func(i models.ItemWithDetails) interface{} { return i.ItemID })},
+			"itemName":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: itemField(func(i models.ItemWithDetails) interface{} { return i.ItemName })},
+			"categoryType": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: itemField(func(i models.ItemWithDetails) interface{} { return i.CategoryType })},
+			"itemCode":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: itemField(func(i models.ItemWithDetails) interface{} { return i.ItemCode })},
+			"locale":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: itemField(func(i models.ItemWithDetails) interface{} { return i.Locale })},
+			"itemNames": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(localizedName))),
+				Resolve: itemField(func(i models.ItemWithDetails) interface{} {
+					names := []map[string]string{}
+					for _, locale := range models.SupportedLocales {
+						if name, ok := i.ItemNames[locale]; ok {
+							names = append(names, map[string]string{"locale": locale, "name": name})
+						}
+					}
+					return names
+				}),
+			},
+		}
+	}
+
+	itemInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:   "Item",
+		Fields: commonFields(),
+	})
+
+	typeAFields := commonFields()
+	typeAFields["capacity"] = &graphql.Field{Type: graphql.Float, Resolve: itemField(func(i models.ItemWithDetails) interface{} {
+		if i.TypeA == nil {
+			return nil
+		}
+		return nullFloat(i.TypeA.Capacity)
+	})}
+	typeAFields["material"] = &graphql.Field{Type: graphql.String, Resolve: itemField(func(i models.ItemWithDetails) interface{} {
+		if i.TypeA == nil || !i.TypeA.Material.Valid {
+			return nil
+		}
+		return i.TypeA.Material.String
+	})}
+	itemTypeA := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "ItemTypeA",
+		Interfaces: []*graphql.Interface{itemInterface},
+		Fields:     typeAFields,
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			i, ok := p.Value.(models.ItemWithDetails)
+			return ok && i.CategoryType == "A"
+		},
+	})
+
+	typeBFields := commonFields()
+	typeBFields["innerDiameter"] = &graphql.Field{Type: graphql.Float, Resolve: itemField(func(i models.ItemWithDetails) interface{} {
+		if i.TypeB == nil {
+			return nil
+		}
+		return nullFloat(i.TypeB.InnerDiameter)
+	})}
+	typeBFields["outerDiameter"] = &graphql.Field{Type: graphql.Float, Resolve: itemField(func(i models.ItemWithDetails) interface{} {
+		if i.TypeB == nil {
+			return nil
+		}
+		return nullFloat(i.TypeB.OuterDiameter)
+	})}
+	itemTypeB := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "ItemTypeB",
+		Interfaces: []*graphql.Interface{itemInterface},
+		Fields:     typeBFields,
+		IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+			i, ok := p.Value.(models.ItemWithDetails)
+			return ok && i.CategoryType == "B"
+		},
+	})
+
+	itemConnection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemConnection",
+		Fields: graphql.Fields{
+			"totalCount":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemInterface)))},
+		},
+	})
+
+	itemFilter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ItemFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"categoryType":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"itemIds":          &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"itemCodePrefix":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"nameContains":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"material":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"minCapacity":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"maxCapacity":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"minInnerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"maxInnerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"minOuterDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"maxOuterDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+
+	localizedNameInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "LocalizedNameInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"locale": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"name":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	createItemInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateItemInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"itemId":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"itemName":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"categoryType":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"itemCode":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"itemNames":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(localizedNameInput))},
+			"capacity":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"material":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"innerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"outerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+
+	updateItemInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateItemInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"itemName":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"itemCode":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"itemNames":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(localizedNameInput))},
+			"capacity":      &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"material":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"innerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"outerDiameter": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"item": &graphql.Field{
+				Type: itemInterface,
+				Args: graphql.FieldConfigArgument{
+					"id":   &graphql.ArgumentConfig{Type: graphql.String},
+					"code": &graphql.ArgumentConfig{Type: graphql.String},
+					"lang": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolveItem,
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(itemConnection),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: itemFilter},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"lang":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolveItems,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createItem": &graphql.Field{
+				Type: itemInterface,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createItemInput)},
+				},
+				Resolve: resolveCreateItem,
+			},
+			"updateItem": &graphql.Field{
+				Type: itemInterface,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateItemInput)},
+				},
+				Resolve: resolveUpdateItem,
+			},
+			"deleteItem": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: resolveDeleteItem,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+		Types:    []graphql.Type{itemTypeA, itemTypeB},
+	})
+}
+
+func itemField(get func(models.ItemWithDetails) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		item, ok := p.Source.(models.ItemWithDetails)
+		if !ok {
+			return nil, nil
+		}
+		return get(item), nil
+	}
+}
+
+func nullFloat(v sql.NullFloat64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Float64
+}
+
+// graphQLLocale は lang 引数、なければリクエストのロケールを返す
+func graphQLLocale(p graphql.ResolveParams) string {
+	if lang, ok := p.Args["lang"].(string); ok {
+		if locale := normalizeLocale(lang); locale != "" {
+			return locale
+		}
+	}
+	if locale, ok := p.Context.Value(localeContextKey{}).(string); ok {
+		return locale
+	}
+	return models.DefaultLocale
+}
+
+func resolveItem(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	code, _ := p.Args["code"].(string)
+	if (id == "") == (code == "") {
+		return nil, errors.New("Specify exactly one of id or code")
+	}
+
+	item, err := lookupItem(id, code)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.New("Failed to fetch item")
+	}
+	return localizeItem(item, graphQLLocale(p), true), nil
+}
+
+func resolveItems(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if first < 1 || first > 100 {
+		return nil, errors.New("first must be between 1 and 100")
+	}
+	if offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	where := "1=1"
+	args := []interface{}{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		where += " AND " + condition + strconv.Itoa(len(args))
+	}
+
+	filter, _ := p.Args["filter"].(map[string]interface{})
+	if v, ok := filter["categoryType"].(string); ok {
+		addCondition("i.品種区分 = $", v)
+	}
+	if v, ok := filter["itemIds"].([]interface{}); ok {
+		ids := []string{}
+		for _, id := range v {
+			ids = append(ids, id.(string))
+		}
+		args = append(args, pq.Array(ids))
+		where += " AND i.品目ID = ANY($" + strconv.Itoa(len(args)) + ")"
+	}
+	if v, ok := filter["itemCodePrefix"].(string); ok {
+		addCondition("i.品目コード LIKE $", escapeLike(v)+"%")
+	}
+	if v, ok := filter["nameContains"].(string); ok {
+		args = append(args, "%"+escapeLike(v)+"%")
+		pos := strconv.Itoa(len(args))
+		where += " AND (i.品目名 LIKE $" + pos + " OR EXISTS (SELECT 1 FROM 品目名称 n WHERE n.品目ID = i.品目ID AND n.品目名 LIKE $" + pos + "))"
+	}
+	if v, ok := filter["material"].(string); ok {
+		addCondition("a.材質 = $", v)
+	}
+	numeric := []struct {
+		arg       string
+		condition string
+	}{
+		{"minCapacity", "a.容量 >= $"},
+		{"maxCapacity", "a.容量 <= $"},
+		{"minInnerDiameter", "b.内径 >= $"},
+		{"maxInnerDiameter", "b.内径 <= $"},
+		{"minOuterDiameter", "b.外径 >= $"},
+		{"maxOuterDiameter", "b.外径 <= $"},
+	}
+	for _, n := range numeric {
+		if v, ok := filter[n.arg].(float64); ok {
+			addCondition(n.condition, v)
+		}
+	}
+
+	from := `
+		FROM 品目基本属性 i
+		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID AND i.品種区分 = 'A'
+		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID AND i.品種区分 = 'B'
+		WHERE ` + where
+
+	var total int
+	if err := DB.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, errors.New("Failed to count items")
+	}
+
+	pageArgs := append(append([]interface{}{}, args...), first, offset)
+	rows, err := DB.Query("SELECT i.品目ID"+from+
+		" ORDER BY i.品目ID LIMIT $"+strconv.Itoa(len(args)+1)+" OFFSET $"+strconv.Itoa(len(args)+2), pageArgs...)
+	if err != nil {
+		return nil, errors.New("Failed to fetch items")
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.New("Failed to fetch items")
+		}
+		ids = append(ids, id)
+	}
+
+	byID, _, err := lookupItems(ids, nil)
+	if err != nil {
+		return nil, errors.New("Failed to fetch items")
+	}
+
+	locale := graphQLLocale(p)
+	items := []interface{}{}
+	for _, id := range ids {
+		if item, ok := byID[id]; ok {
+			items = append(items, localizeItem(item, locale, true))
+		}
+	}
+
+	return map[string]interface{}{
+		"totalCount":  total,
+		"hasNextPage": offset+len(ids) < total,
+		"items":       items,
+	}, nil
+}
+
+func resolveCreateItem(p graphql.ResolveParams) (interface{}, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := models.ItemCreateRequest{
+		ItemID:        input["itemId"].(string),
+		ItemName:      input["itemName"].(string),
+		CategoryType:  input["categoryType"].(string),
+		ItemCode:      input["itemCode"].(string),
+		ItemNames:     localizedNames(input["itemNames"]),
+		Capacity:      optionalFloat(input["capacity"]),
+		Material:      optionalString(input["material"]),
+		InnerDiameter: optionalFloat(input["innerDiameter"]),
+		OuterDiameter: optionalFloat(input["outerDiameter"]),
+	}
+
+	if err := runItemTx(func(tx *sql.Tx) *itemError { return createItemTx(tx, req) }); err != nil {
+		return nil, err
+	}
+	ItemCache.Invalidate(req.ItemID)
+
+	return resolveMutatedItem(p, req.ItemID)
+}
+
+func resolveUpdateItem(p graphql.ResolveParams) (interface{}, error) {
+	itemID := p.Args["id"].(string)
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := models.ItemUpdateRequest{
+		ItemName:      optionalString(input["itemName"]),
+		ItemCode:      optionalString(input["itemCode"]),
+		ItemNames:     localizedNames(input["itemNames"]),
+		Capacity:      optionalFloat(input["capacity"]),
+		Material:      optionalString(input["material"]),
+		InnerDiameter: optionalFloat(input["innerDiameter"]),
+		OuterDiameter: optionalFloat(input["outerDiameter"]),
+	}
+
+	if err := runItemTx(func(tx *sql.Tx) *itemError { return updateItemTx(tx, itemID, req) }); err != nil {
+		return nil, err
+	}
+	ItemCache.Invalidate(itemID)
+
+	return resolveMutatedItem(p, itemID)
+}
+
+func resolveDeleteItem(p graphql.ResolveParams) (interface{}, error) {
+	itemID := p.Args["id"].(string)
+
+	var attachmentKeys []string
+	err := runItemTx(func(tx *sql.Tx) *itemError {
+		var ierr *itemError
+		attachmentKeys, ierr = deleteItemTx(tx, itemID)
+		return ierr
+	})
+	if err != nil {
+		return nil, err
+	}
+	ItemCache.Invalidate(itemID)
+	removeAttachmentFiles(attachmentKeys)
+
+	return true, nil
+}
+
+func resolveMutatedItem(p graphql.ResolveParams, itemID string) (interface{}, error) {
+	item, err := lookupItem(itemID, "")
+	if err != nil {
+		return nil, errors.New("Failed to fetch item")
+	}
+	return localizeItem(item, graphQLLocale(p), true), nil
+}
+
+// runItemTx は品目の書き込み処理を1トランザクションで実行する
+func runItemTx(fn func(tx *sql.Tx) *itemError) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return errors.New("Failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if ierr := fn(tx); ierr != nil {
+		return ierr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("Failed to commit transaction")
+	}
+	return nil
+}
+
+func localizedNames(v interface{}) map[string]string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	names := map[string]string{}
+	for _, entry := range list {
+		m := entry.(map[string]interface{})
+		names[m["locale"].(string)] = m["name"].(string)
+	}
+	return names
+}
+
+func optionalString(v interface{}) *string {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	return &s
+}
+
+func optionalFloat(v interface{}) *float64 {
+	f, ok := v.(float64)
+	if !ok {
+		return nil
+	}
+	return &f
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 品種区分と品種別属性テーブルの対応
+var subtypeTables = map[string]string{
+	"A": "A品種品目属性",
+	"B": "B品種品目属性",
+}
+
+func CheckItemIntegrity(c echo.Context) error {
+	report, err := CheckIntegrity(false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to check item integrity",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+func RepairItemIntegrity(c echo.Context) error {
+	report, err := CheckIntegrity(true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to repair item integrity: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: fmt.Sprintf("%d of %d issues repaired", report.Repaired, len(report.Issues)),
+		Data:    report,
+	})
+}
+
+// CheckIntegrity は品目基本属性と品種別属性の対応、品目コードの形式を検査する。
+// repair が true の場合は修復可能な問題を1トランザクションで修復する。
+// 修復中は検査対象のテーブルを書き込みロックするため、他の更新は完了まで待たされる。
+func CheckIntegrity(repair bool) (models.IntegrityReport, error) {
+	report := models.IntegrityReport{
+		IssueCounts: map[string]int{},
+		Issues:      []models.IntegrityIssue{},
+		RepairMode:  repair,
+		Timestamp:   time.Now(),
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	if repair {
+		if _, err := tx.Exec("LOCK TABLE 品目基本属性, A品種品目属性, B品種品目属性 IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return report, err
+		}
+	}
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM 品目基本属性").Scan(&report.Checked); err != nil {
+		return report, err
+	}
+
+	issues, err := findOrphanedSubtypes(tx)
+	if err != nil {
+		return report, err
+	}
+	report.Issues = append(report.Issues, issues...)
+
+	issues, err = findSubtypeMismatches(tx)
+	if err != nil {
+		return report, err
+	}
+	report.Issues = append(report.Issues, issues...)
+
+	issues, err = findInvalidItemCodes(tx)
+	if err != nil {
+		return report, err
+	}
+	report.Issues = append(report.Issues, issues...)
+
+	for _, issue := range report.Issues {
+		report.IssueCounts[issue.Type]++
+	}
+
+	if !repair {
+		return report, nil
+	}
+
+	changedItems, err := repairIssues(tx, report.Issues)
+	if err != nil {
+		return report, err
+	}
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+
+	for i := range report.Issues {
+		if report.Issues[i].Repair != "" {
+			report.Issues[i].Repaired = true
+			report.Repaired++
+		}
+	}
+	for _, itemID := range changedItems {
+		ItemCache.Invalidate(itemID)
+	}
+
+	return report, nil
+}
+
+// findOrphanedSubtypes は品目基本属性に存在しない品目IDを持つ品種別属性の行を探す
+func findOrphanedSubtypes(tx *sql.Tx) ([]models.IntegrityIssue, error) {
+	rows, err := tx.Query(`
+		SELECT 'A品種品目属性', s.品目ID
+		FROM A品種品目属性 s
+		LEFT JOIN 品目基本属性 i ON s.品目ID = i.品目ID
+		WHERE i.品目ID IS NULL
+		UNION ALL
+		SELECT 'B品種品目属性', s.品目ID
+		FROM B品種品目属性 s
+		LEFT JOIN 品目基本属性 i ON s.品目ID = i.品目ID
+		WHERE i.品目ID IS NULL
+		ORDER BY 2`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []models.IntegrityIssue{}
+	for rows.Next() {
+		issue := models.IntegrityIssue{Type: models.IssueOrphanedSubtype}
+		if err := rows.Scan(&issue.SubtypeTable, &issue.ItemID); err != nil {
+			return nil, err
+		}
+		issue.Detail = fmt.Sprintf("%s row has no matching item", issue.SubtypeTable)
+		issue.Repair = fmt.Sprintf("delete the %s row", issue.SubtypeTable)
+		issues = append(issues, issue)
+	}
+	return issues, rows.Err()
+}
+
+// findSubtypeMismatches は品種区分に対応する品種別属性の行がない品目と、
+// 他の品種の品種別属性の行を持つ品目を探す
+func findSubtypeMismatches(tx *sql.Tx) ([]models.IntegrityIssue, error) {
+	rows, err := tx.Query(`
+		SELECT i.品目ID, i.品種区分, i.品目コード,
+			   a.品目ID IS NOT NULL, a.容量, a.材質,
+			   b.品目ID IS NOT NULL, b.内径, b.外径
+		FROM 品目基本属性 i
+		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID
+		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID
+		WHERE (i.品種区分 = 'A' AND (a.品目ID IS NULL OR b.品目ID IS NOT NULL))
+		   OR (i.品種区分 = 'B' AND (b.品目ID IS NULL OR a.品目ID IS NOT NULL))
+		ORDER BY i.品目ID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []models.IntegrityIssue{}
+	for rows.Next() {
+		var itemID, categoryType, itemCode string
+		var hasA, hasB bool
+		var capacity, innerDiameter, outerDiameter sql.NullFloat64
+		var material sql.NullString
+		if err := rows.Scan(
+			&itemID, &categoryType, &itemCode,
+			&hasA, &capacity, &material,
+			&hasB, &innerDiameter, &outerDiameter,
+		); err != nil {
+			return nil, err
+		}
+
+		has := map[string]bool{"A": hasA, "B": hasB}
+		attributes := map[string]string{
+			"A": fmt.Sprintf("容量=%s, 材質=%s", formatNullFloat(capacity), formatNullString(material)),
+			"B": fmt.Sprintf("内径=%s, 外径=%s", formatNullFloat(innerDiameter), formatNullFloat(outerDiameter)),
+		}
+
+		if !has[categoryType] {
+			table := subtypeTables[categoryType]
+			issues = append(issues, models.IntegrityIssue{
+				Type:         models.IssueMissingSubtype,
+				ItemID:       itemID,
+				CategoryType: &categoryType,
+				ItemCode:     &itemCode,
+				SubtypeTable: table,
+				Detail:       fmt.Sprintf("category %s item has no %s row", categoryType, table),
+				Repair:       fmt.Sprintf("insert an empty %s row", table),
+			})
+		}
+		for _, other := range []string{"A", "B"} {
+			if other == categoryType || !has[other] {
+				continue
+			}
+			table := subtypeTables[other]
+			issues = append(issues, models.IntegrityIssue{
+				Type:         models.IssueMismatchedSubtype,
+				ItemID:       itemID,
+				CategoryType: &categoryType,
+				ItemCode:     &itemCode,
+				SubtypeTable: table,
+				Detail:       fmt.Sprintf("category %s item has a %s row (%s)", categoryType, table, attributes[other]),
+				Repair:       fmt.Sprintf("delete the %s row", table),
+			})
+		}
+	}
+	return issues, rows.Err()
+}
+
+// findInvalidItemCodes は形式に合わない品目コードを探す。
+// 前後の空白除去と大文字化で形式を満たし、他の品目と重複しない場合は修復対象とする
+func findInvalidItemCodes(tx *sql.Tx) ([]models.IntegrityIssue, error) {
+	rows, err := tx.Query("SELECT 品目ID, 品種区分, 品目コード FROM 品目基本属性 ORDER BY 品目ID")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usedCodes := map[string]bool{}
+	var invalid []models.IntegrityIssue
+	for rows.Next() {
+		var itemID, categoryType, itemCode string
+		if err := rows.Scan(&itemID, &categoryType, &itemCode); err != nil {
+			return nil, err
+		}
+		usedCodes[itemCode] = true
+		if models.ItemCodePattern.MatchString(itemCode) {
+			continue
+		}
+		invalid = append(invalid, models.IntegrityIssue{
+			Type:         models.IssueInvalidItemCode,
+			ItemID:       itemID,
+			CategoryType: &categoryType,
+			ItemCode:     &itemCode,
+			Detail:       fmt.Sprintf("item code %q does not match %s", itemCode, models.ItemCodePattern.String()),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	issues := []models.IntegrityIssue{}
+	for _, issue := range invalid {
+		normalized := strings.ToUpper(strings.TrimSpace(*issue.ItemCode))
+		if models.ItemCodePattern.MatchString(normalized) && !usedCodes[normalized] {
+			issue.Repair = fmt.Sprintf("change item code to %q", normalized)
+			usedCodes[normalized] = true
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// repairIssues は修復可能な問題を修復し、変更した品目の品目IDを返す。
+// 品目基本属性が存在する品目には item.updated イベントを記録する
+func repairIssues(tx *sql.Tx, issues []models.IntegrityIssue) ([]string, error) {
+	changed := map[string]bool{}
+	for _, issue := range issues {
+		if issue.Repair == "" {
+			continue
+		}
+
+		var err error
+		switch issue.Type {
+		case models.IssueOrphanedSubtype, models.IssueMismatchedSubtype:
+			_, err = tx.Exec("DELETE FROM "+issue.SubtypeTable+" WHERE 品目ID = $1", issue.ItemID)
+		case models.IssueMissingSubtype:
+			_, err = tx.Exec("INSERT INTO "+issue.SubtypeTable+" (品目ID) VALUES ($1)", issue.ItemID)
+		case models.IssueInvalidItemCode:
+			normalized := strings.ToUpper(strings.TrimSpace(*issue.ItemCode))
+			_, err = tx.Exec("UPDATE 品目基本属性 SET 品目コード = $1 WHERE 品目ID = $2", normalized, issue.ItemID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", issue.Type, issue.ItemID, err)
+		}
+
+		if issue.Type != models.IssueOrphanedSubtype {
+			changed[issue.ItemID] = true
+		}
+	}
+
+	itemIDs := make([]string, 0, len(changed))
+	for itemID := range changed {
+		itemIDs = append(itemIDs, itemID)
+	}
+	sort.Strings(itemIDs)
+
+	for _, itemID := range itemIDs {
+		if err := recordItemEvent(tx, models.EventItemUpdated, itemID); err != nil {
+			return nil, fmt.Errorf("failed to record item event for %s: %v", itemID, err)
+		}
+		if err := notifyItemChanged(tx, itemID); err != nil {
+			return nil, fmt.Errorf("failed to notify item change for %s: %v", itemID, err)
+		}
+	}
+	return itemIDs, nil
+}
+
+func formatNullFloat(v sql.NullFloat64) string {
+	if !v.Valid {
+		return "NULL"
+	}
+	return fmt.Sprintf("%g", v.Float64)
+}
+
+func formatNullString(v sql.NullString) string {
+	if !v.Valid {
+		return "NULL"
+	}
+	return v.String
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 	"strconv"
 
+	"code-system/events"
 	"code-system/models"
+	"code-system/storage"
 
 	"github.com/labstack/echo/v4"
 )
@@ -28,27 +31,30 @@ func GetItems(c echo.Context) error {
 	}
 	
 	categoryType := c.QueryParam("category_type")
+	locale := resolveLocale(c)
 	
 	offset := (page - 1) * pageSize
 	
 	query := `
-		SELECT i.品目ID, i.品目名, i.品種区分, i.品目コード,
+		SELECT i.品目ID, COALESCE(n.品目名, i.品目名), i.品種区分, i.品目コード,
+			   CASE WHEN n.品目名 IS NULL THEN '` + models.DefaultLocale + `' ELSE n.言語コード END,
 			   a.容量, a.材質,
 			   b.内径, b.外径
 		FROM 品目基本属性 i
+		LEFT JOIN 品目名称 n ON i.品目ID = n.品目ID AND n.言語コード = $1
 		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID AND i.品種区分 = 'A'
 		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID AND i.品種区分 = 'B'
 		WHERE 1=1`
 	
 	countQuery := "SELECT COUNT(*) FROM 品目基本属性 WHERE 1=1"
 	
-	args := []interface{}{}
+	args := []interface{}{locale}
 	countArgs := []interface{}{}
-	argPos := 1
+	argPos := 2
 	
 	if categoryType != "" {
 		query += " AND i.品種区分 = $" + strconv.Itoa(argPos)
-		countQuery += " AND 品種区分 = $" + strconv.Itoa(argPos)
+		countQuery += " AND 品種区分 = $" + strconv.Itoa(len(countArgs)+1)
 		args = append(args, categoryType)
 		countArgs = append(countArgs, categoryType)
 		argPos++
@@ -82,7 +88,7 @@ func GetItems(c echo.Context) error {
 		var typeB models.ItemTypeB
 		
 		err := rows.Scan(
-			&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode,
+			&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode, &item.Locale,
 			&typeA.Capacity, &typeA.Material,
 			&typeB.InnerDiameter, &typeB.OuterDiameter,
 		)
@@ -114,27 +120,7 @@ func GetItems(c echo.Context) error {
 }
 
 func GetItem(c echo.Context) error {
-	itemID := c.Param("id")
-	
-	query := `
-		SELECT i.品目ID, i.品目名, i.品種区分, i.品目コード,
-			   a.容量, a.材質,
-			   b.内径, b.外径
-		FROM 品目基本属性 i
-		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID
-		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID
-		WHERE i.品目ID = $1`
-	
-	var item models.ItemWithDetails
-	var typeA models.ItemTypeA
-	var typeB models.ItemTypeB
-	
-	err := DB.QueryRow(query, itemID).Scan(
-		&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode,
-		&typeA.Capacity, &typeA.Material,
-		&typeB.InnerDiameter, &typeB.OuterDiameter,
-	)
-	
+	item, err := lookupItem(c.Param("id"), "")
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, models.Response{
 			Success: false,
@@ -147,21 +133,22 @@ func GetItem(c echo.Context) error {
 		})
 	}
 	
-	typeA.ItemID = item.ItemID
-	typeB.ItemID = item.ItemID
-	
-	if item.CategoryType == "A" && (typeA.Capacity.Valid || typeA.Material.Valid) {
-		item.TypeA = &typeA
-	} else if item.CategoryType == "B" && (typeB.InnerDiameter.Valid || typeB.OuterDiameter.Valid) {
-		item.TypeB = &typeB
-	}
-	
 	return c.JSON(http.StatusOK, models.Response{
 		Success: true,
-		Data:    item,
+		Data:    localizeItem(item, resolveLocale(c), true),
 	})
 }
 
+// itemError は品目の書き込み処理が返すエラーで、レスポンスのステータスコードを伴う
+type itemError struct {
+	status  int
+	message string
+}
+
+func (e *itemError) Error() string {
+	return e.message
+}
+
 func CreateItem(c echo.Context) error {
 	var req models.ItemCreateRequest
 	if err := c.Bind(&req); err != nil {
@@ -171,13 +158,6 @@ func CreateItem(c echo.Context) error {
 		})
 	}
 	
-	if req.CategoryType != "A" && req.CategoryType != "B" {
-		return c.JSON(http.StatusBadRequest, models.Response{
-			Success: false,
-			Error:   "Category type must be 'A' or 'B'",
-		})
-	}
-	
 	tx, err := DB.Begin()
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, models.Response{
@@ -187,33 +167,10 @@ func CreateItem(c echo.Context) error {
 	}
 	defer tx.Rollback()
 	
-	_, err = tx.Exec(
-		"INSERT INTO 品目基本属性 (品目ID, 品目名, 品種区分, 品目コード) VALUES ($1, $2, $3, $4)",
-		req.ItemID, req.ItemName, req.CategoryType, req.ItemCode,
-	)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, models.Response{
+	if ierr := createItemTx(tx, req); ierr != nil {
+		return c.JSON(ierr.status, models.Response{
 			Success: false,
-			Error:   "Failed to create item: " + err.Error(),
-		})
-	}
-	
-	if req.CategoryType == "A" {
-		_, err = tx.Exec(
-			"INSERT INTO A品種品目属性 (品目ID, 容量, 材質) VALUES ($1, $2, $3)",
-			req.ItemID, req.Capacity, req.Material,
-		)
-	} else if req.CategoryType == "B" {
-		_, err = tx.Exec(
-			"INSERT INTO B品種品目属性 (品目ID, 内径, 外径) VALUES ($1, $2, $3)",
-			req.ItemID, req.InnerDiameter, req.OuterDiameter,
-		)
-	}
-	
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.Response{
-			Success: false,
-			Error:   "Failed to create item attributes",
+			Error:   ierr.message,
 		})
 	}
 	
@@ -223,7 +180,10 @@ func CreateItem(c echo.Context) error {
 			Error:   "Failed to commit transaction",
 		})
 	}
+	ItemCache.Invalidate(req.ItemID)
 	
+	c.SetParamNames("id")
+	c.SetParamValues(req.ItemID)
 	return GetItem(c)
 }
 
@@ -238,19 +198,35 @@ func UpdateItem(c echo.Context) error {
 		})
 	}
 	
-	var categoryType string
-	err := DB.QueryRow("SELECT 品種区分 FROM 品目基本属性 WHERE 品目ID = $1", itemID).Scan(&categoryType)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, models.Response{
+	tx, err := DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
 			Success: false,
-			Error:   "Item not found",
+			Error:   "Failed to start transaction",
 		})
-	} else if err != nil {
+	}
+	defer tx.Rollback()
+	
+	if ierr := updateItemTx(tx, itemID, req); ierr != nil {
+		return c.JSON(ierr.status, models.Response{
+			Success: false,
+			Error:   ierr.message,
+		})
+	}
+	
+	if err = tx.Commit(); err != nil {
 		return c.JSON(http.StatusInternalServerError, models.Response{
 			Success: false,
-			Error:   "Failed to fetch item",
+			Error:   "Failed to commit transaction",
 		})
 	}
+	ItemCache.Invalidate(itemID)
+	
+	return GetItem(c)
+}
+
+func DeleteItem(c echo.Context) error {
+	itemID := c.Param("id")
 	
 	tx, err := DB.Begin()
 	if err != nil {
@@ -261,6 +237,102 @@ func UpdateItem(c echo.Context) error {
 	}
 	defer tx.Rollback()
 	
+	attachmentKeys, ierr := deleteItemTx(tx, itemID)
+	if ierr != nil {
+		return c.JSON(ierr.status, models.Response{
+			Success: false,
+			Error:   ierr.message,
+		})
+	}
+	
+	if err = tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to commit transaction",
+		})
+	}
+	
+	ItemCache.Invalidate(itemID)
+	removeAttachmentFiles(attachmentKeys)
+	
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: "Item deleted successfully",
+	})
+}
+
+const invalidItemCodeMessage = "Item code must be uppercase letters and digits joined by hyphens (e.g. PBOT-500)"
+
+func createItemTx(tx *sql.Tx, req models.ItemCreateRequest) *itemError {
+	if req.CategoryType != "A" && req.CategoryType != "B" {
+		return &itemError{http.StatusBadRequest, "Category type must be 'A' or 'B'"}
+	}
+	
+	if !models.ItemCodePattern.MatchString(req.ItemCode) {
+		return &itemError{http.StatusBadRequest, invalidItemCodeMessage}
+	}
+	
+	if err := validateItemNames(req.ItemNames); err != nil {
+		return &itemError{http.StatusBadRequest, err.Error()}
+	}
+	
+	_, err := tx.Exec(
+		"INSERT INTO 品目基本属性 (品目ID, 品目名, 品種区分, 品目コード) VALUES ($1, $2, $3, $4)",
+		req.ItemID, req.ItemName, req.CategoryType, req.ItemCode,
+	)
+	if err != nil {
+		return &itemError{http.StatusBadRequest, "Failed to create item: " + err.Error()}
+	}
+	
+	if req.CategoryType == "A" {
+		_, err = tx.Exec(
+			"INSERT INTO A品種品目属性 (品目ID, 容量, 材質) VALUES ($1, $2, $3)",
+			req.ItemID, req.Capacity, req.Material,
+		)
+	} else if req.CategoryType == "B" {
+		_, err = tx.Exec(
+			"INSERT INTO B品種品目属性 (品目ID, 内径, 外径) VALUES ($1, $2, $3)",
+			req.ItemID, req.InnerDiameter, req.OuterDiameter,
+		)
+	}
+	
+	if err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to create item attributes"}
+	}
+	
+	if err = saveItemNames(tx, req.ItemID, req.ItemNames); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to create item names"}
+	}
+	
+	if err = recordItemEvent(tx, models.EventItemCreated, req.ItemID); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to record item event"}
+	}
+	
+	if err = notifyItemChanged(tx, req.ItemID); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to notify item change"}
+	}
+	
+	return nil
+}
+
+func updateItemTx(tx *sql.Tx, itemID string, req models.ItemUpdateRequest) *itemError {
+	// 形式に合わない既存の品目コードは整合性チェックで扱うため、変更する場合のみ検証する
+	if req.ItemCode != nil && !models.ItemCodePattern.MatchString(*req.ItemCode) {
+		return &itemError{http.StatusBadRequest, invalidItemCodeMessage}
+	}
+	
+	if err := validateItemNames(req.ItemNames); err != nil {
+		return &itemError{http.StatusBadRequest, err.Error()}
+	}
+	
+	var categoryType string
+	err := tx.QueryRow("SELECT 品種区分 FROM 品目基本属性 WHERE 品目ID = $1", itemID).Scan(&categoryType)
+	if err == sql.ErrNoRows {
+		return &itemError{http.StatusNotFound, "Item not found"}
+	} else if err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to fetch item"}
+	}
+	
 	if req.ItemName != nil || req.ItemCode != nil {
 		updateQuery := "UPDATE 品目基本属性 SET "
 		args := []interface{}{}
@@ -284,10 +356,7 @@ func UpdateItem(c echo.Context) error {
 		
 		_, err = tx.Exec(updateQuery, args...)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, models.Response{
-				Success: false,
-				Error:   "Failed to update item",
-			})
+			return &itemError{http.StatusBadRequest, "Failed to update item"}
 		}
 	}
 	
@@ -314,10 +383,7 @@ func UpdateItem(c echo.Context) error {
 		
 		_, err = tx.Exec(updateQuery, args...)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, models.Response{
-				Success: false,
-				Error:   "Failed to update item attributes",
-			})
+			return &itemError{http.StatusInternalServerError, "Failed to update item attributes"}
 		}
 	} else if categoryType == "B" && (req.InnerDiameter != nil || req.OuterDiameter != nil) {
 		updateQuery := "UPDATE B品種品目属性 SET "
@@ -342,44 +408,113 @@ func UpdateItem(c echo.Context) error {
 		
 		_, err = tx.Exec(updateQuery, args...)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, models.Response{
-				Success: false,
-				Error:   "Failed to update item attributes",
-			})
+			return &itemError{http.StatusInternalServerError, "Failed to update item attributes"}
 		}
 	}
 	
-	if err = tx.Commit(); err != nil {
-		return c.JSON(http.StatusInternalServerError, models.Response{
-			Success: false,
-			Error:   "Failed to commit transaction",
-		})
+	if err = saveItemNames(tx, itemID, req.ItemNames); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to update item names"}
 	}
 	
-	return GetItem(c)
+	if err = recordItemEvent(tx, models.EventItemUpdated, itemID); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to record item event"}
+	}
+	
+	if err = notifyItemChanged(tx, itemID); err != nil {
+		return &itemError{http.StatusInternalServerError, "Failed to notify item change"}
+	}
+	
+	return nil
 }
 
-func DeleteItem(c echo.Context) error {
-	itemID := c.Param("id")
+// deleteItemTx は品目を削除し、コミット後にストレージから消すべき添付ファイルの保存キーを返す
+func deleteItemTx(tx *sql.Tx, itemID string) ([]string, *itemError) {
+	snapshot, err := fetchItemSnapshot(tx, itemID)
+	if err == sql.ErrNoRows {
+		return nil, &itemError{http.StatusNotFound, "Item not found"}
+	} else if err != nil {
+		return nil, &itemError{http.StatusInternalServerError, "Failed to fetch item"}
+	}
 	
-	result, err := DB.Exec("DELETE FROM 品目基本属性 WHERE 品目ID = $1", itemID)
+	attachmentKeys, err := attachmentStorageKeys(tx, itemID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.Response{
-			Success: false,
-			Error:   "Failed to delete item",
-		})
+		return nil, &itemError{http.StatusInternalServerError, "Failed to fetch attachments"}
+	}
+	
+	result, err := tx.Exec("DELETE FROM 品目基本属性 WHERE 品目ID = $1", itemID)
+	if err != nil {
+		return nil, &itemError{http.StatusInternalServerError, "Failed to delete item"}
 	}
 	
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		return c.JSON(http.StatusNotFound, models.Response{
-			Success: false,
-			Error:   "Item not found",
-		})
+		return nil, &itemError{http.StatusNotFound, "Item not found"}
 	}
 	
-	return c.JSON(http.StatusOK, models.Response{
-		Success: true,
-		Message: "Item deleted successfully",
-	})
-}
\ No newline at end of file
+	if err = events.Record(tx, models.EventItemDeleted, itemID, snapshot); err != nil {
+		return nil, &itemError{http.StatusInternalServerError, "Failed to record item event"}
+	}
+	
+	if err = notifyItemChanged(tx, itemID); err != nil {
+		return nil, &itemError{http.StatusInternalServerError, "Failed to notify item change"}
+	}
+	
+	return attachmentKeys, nil
+}
+
+func removeAttachmentFiles(keys []string) {
+	if Storage == nil && len(keys) > 0 {
+		log.Printf("attachment storage is not configured; %d attachment files were left in place", len(keys))
+		return
+	}
+	for _, key := range keys {
+		if err := Storage.Delete(key); err != nil && err != storage.ErrNotFound {
+			log.Printf("failed to remove attachment file %s: %v", key, err)
+		}
+	}
+}
+
+// fetchItemSnapshot はイベントのペイロードとして、トランザクション内から見た品目の状態を取得する
+func fetchItemSnapshot(tx *sql.Tx, itemID string) (*models.ItemWithDetails, error) {
+	query := `
+		SELECT i.品目ID, i.品目名, i.品種区分, i.品目コード,
+			   a.容量, a.材質,
+			   b.内径, b.外径
+		FROM 品目基本属性 i
+		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID
+		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID
+		WHERE i.品目ID = $1`
+	
+	var item models.ItemWithDetails
+	var typeA models.ItemTypeA
+	var typeB models.ItemTypeB
+	
+	err := tx.QueryRow(query, itemID).Scan(
+		&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode,
+		&typeA.Capacity, &typeA.Material,
+		&typeB.InnerDiameter, &typeB.OuterDiameter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	
+	item.Locale = models.DefaultLocale
+	typeA.ItemID = item.ItemID
+	typeB.ItemID = item.ItemID
+	
+	if item.CategoryType == "A" && (typeA.Capacity.Valid || typeA.Material.Valid) {
+		item.TypeA = &typeA
+	} else if item.CategoryType == "B" && (typeB.InnerDiameter.Valid || typeB.OuterDiameter.Valid) {
+		item.TypeB = &typeB
+	}
+	
+	return &item, nil
+}
+
+func recordItemEvent(tx *sql.Tx, eventType, itemID string) error {
+	snapshot, err := fetchItemSnapshot(tx, itemID)
+	if err != nil {
+		return err
+	}
+	return events.Record(tx, eventType, itemID, snapshot)
+}
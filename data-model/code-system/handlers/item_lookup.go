@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"code-system/cache"
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+var ItemCache *cache.ItemCache
+
+func SetItemCache(c *cache.ItemCache) {
+	ItemCache = c
+}
+
+func GetItemByCode(c echo.Context) error {
+	item, err := lookupItem("", c.Param("code"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Item not found",
+		})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch item",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    localizeItem(item, resolveLocale(c), true),
+	})
+}
+
+func GetCacheStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    ItemCache.Stats(),
+	})
+}
+
+// lookupItem は品目IDまたは品目コードで品目を取得する。キャッシュにない場合は DB から読み込んで格納する
+func lookupItem(itemID, itemCode string) (models.ItemWithDetails, error) {
+	var item models.ItemWithDetails
+	var ok bool
+	if itemID != "" {
+		item, ok = ItemCache.GetByID(itemID)
+	} else {
+		item, ok = ItemCache.GetByCode(itemCode)
+	}
+	if ok {
+		return item, nil
+	}
+
+	version := ItemCache.Version()
+	var items []models.ItemWithDetails
+	var err error
+	if itemID != "" {
+		items, err = loadItems("i.品目ID = $1", itemID)
+	} else {
+		items, err = loadItems("i.品目コード = $1", itemCode)
+	}
+	if err != nil {
+		return models.ItemWithDetails{}, err
+	}
+	if len(items) == 0 {
+		return models.ItemWithDetails{}, sql.ErrNoRows
+	}
+
+	ItemCache.Put(items[0], version)
+	return items[0], nil
+}
+
+// lookupItems は複数の品目IDと品目コードをまとめて取得し、キャッシュにないものだけを DB から読み込む
+func lookupItems(itemIDs, itemCodes []string) (map[string]models.ItemWithDetails, map[string]models.ItemWithDetails, error) {
+	byID := map[string]models.ItemWithDetails{}
+	byCode := map[string]models.ItemWithDetails{}
+
+	missingIDs := []string{}
+	for _, id := range itemIDs {
+		if item, ok := ItemCache.GetByID(id); ok {
+			byID[item.ItemID] = item
+			byCode[item.ItemCode] = item
+		} else {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	missingCodes := []string{}
+	for _, code := range itemCodes {
+		if item, ok := ItemCache.GetByCode(code); ok {
+			byID[item.ItemID] = item
+			byCode[item.ItemCode] = item
+		} else {
+			missingCodes = append(missingCodes, code)
+		}
+	}
+	if len(missingIDs)+len(missingCodes) == 0 {
+		return byID, byCode, nil
+	}
+
+	version := ItemCache.Version()
+	items, err := loadItems("i.品目ID = ANY($1) OR i.品目コード = ANY($2)", pq.Array(missingIDs), pq.Array(missingCodes))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, item := range items {
+		ItemCache.Put(item, version)
+		byID[item.ItemID] = item
+		byCode[item.ItemCode] = item
+	}
+
+	return byID, byCode, nil
+}
+
+// loadItems はロケールに依存しない品目の内容（全ロケールの品目名を含む）を読み込む
+func loadItems(where string, args ...interface{}) ([]models.ItemWithDetails, error) {
+	query := `
+		SELECT i.品目ID, i.品目名, i.品種区分, i.品目コード,
+			   a.容量, a.材質,
+			   b.内径, b.外径,
+			   COALESCE(json_object_agg(n.言語コード, n.品目名) FILTER (WHERE n.言語コード IS NOT NULL), '{}')
+		FROM 品目基本属性 i
+		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID AND i.品種区分 = 'A'
+		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID AND i.品種区分 = 'B'
+		LEFT JOIN 品目名称 n ON i.品目ID = n.品目ID
+		WHERE ` + where + `
+		GROUP BY i.品目ID, a.容量, a.材質, b.内径, b.外径
+		ORDER BY i.品目ID`
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ItemWithDetails{}
+	for rows.Next() {
+		var item models.ItemWithDetails
+		var typeA models.ItemTypeA
+		var typeB models.ItemTypeB
+		var names []byte
+
+		err := rows.Scan(
+			&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode,
+			&typeA.Capacity, &typeA.Material,
+			&typeB.InnerDiameter, &typeB.OuterDiameter,
+			&names,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Locale = models.DefaultLocale
+		item.ItemNames = map[string]string{}
+		if err := json.Unmarshal(names, &item.ItemNames); err != nil {
+			return nil, err
+		}
+		item.ItemNames[models.DefaultLocale] = item.ItemName
+
+		typeA.ItemID = item.ItemID
+		typeB.ItemID = item.ItemID
+
+		if item.CategoryType == "A" && (typeA.Capacity.Valid || typeA.Material.Valid) {
+			item.TypeA = &typeA
+		} else if item.CategoryType == "B" && (typeB.InnerDiameter.Valid || typeB.OuterDiameter.Valid) {
+			item.TypeB = &typeB
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// localizeItem は品目名を指定ロケールのものに置き換える。翻訳がなければ日本語名のままとする
+func localizeItem(item models.ItemWithDetails, locale string, withNames bool) models.ItemWithDetails {
+	if name, ok := item.ItemNames[locale]; ok {
+		item.ItemName = name
+		item.Locale = locale
+	} else {
+		item.ItemName = item.ItemNames[models.DefaultLocale]
+		item.Locale = models.DefaultLocale
+	}
+	if !withNames {
+		item.ItemNames = nil
+	}
+	return item
+}
+
+// notifyItemChanged はコミット時に他インスタンスのキャッシュを無効化するための通知を発行する
+func notifyItemChanged(tx *sql.Tx, itemID string) error {
+	_, err := tx.Exec("SELECT pg_notify($1, $2)", cache.NotifyChannel, itemID)
+	return err
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"code-system/models"
+)
+
+// validateItemNames は既定ロケール以外の対応ロケールのみを受け付ける（既定ロケールは item_name で指定する）
+func validateItemNames(names map[string]string) error {
+	for locale, name := range names {
+		if locale == models.DefaultLocale {
+			return fmt.Errorf("Use item_name for the '%s' item name", models.DefaultLocale)
+		}
+		if !models.IsSupportedLocale(locale) {
+			return fmt.Errorf("Unsupported locale '%s'", locale)
+		}
+		if len([]rune(name)) > 100 {
+			return fmt.Errorf("Item name for '%s' must be at most 100 characters", locale)
+		}
+	}
+	return nil
+}
+
+// saveItemNames は翻訳名を登録・更新する。空文字の場合は翻訳を削除する
+func saveItemNames(tx *sql.Tx, itemID string, names map[string]string) error {
+	for locale, name := range names {
+		var err error
+		if name == "" {
+			_, err = tx.Exec("DELETE FROM 品目名称 WHERE 品目ID = $1 AND 言語コード = $2", itemID, locale)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO 品目名称 (品目ID, 言語コード, 品目名) VALUES ($1, $2, $3)
+				ON CONFLICT (品目ID, 言語コード) DO UPDATE SET 品目名 = EXCLUDED.品目名`,
+				itemID, locale, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"code-system/models"
+)
+
+// 以下は HTTP を介さずに品目を読み書きするための関数で、cmd/itemctl から利用する。
+// 書き込みは API と同じ処理を通るため、イベントの記録とキャッシュ無効化の通知も行われる。
+
+func FindItem(itemID, itemCode string) (models.ItemWithDetails, error) {
+	return lookupItem(itemID, itemCode)
+}
+
+func ListItems(categoryType string) ([]models.ItemWithDetails, error) {
+	if categoryType == "" {
+		return loadItems("1=1")
+	}
+	return loadItems("i.品種区分 = $1", categoryType)
+}
+
+// WithItemTx は fn を1トランザクションで実行し、エラーがなければコミットする
+func WithItemTx(fn func(tx *sql.Tx) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func CreateItemInTx(tx *sql.Tx, req models.ItemCreateRequest) error {
+	if ierr := createItemTx(tx, req); ierr != nil {
+		return ierr
+	}
+	return nil
+}
+
+func UpdateItemInTx(tx *sql.Tx, itemID string, req models.ItemUpdateRequest) error {
+	if ierr := updateItemTx(tx, itemID, req); ierr != nil {
+		return ierr
+	}
+	return nil
+}
+
+// DeleteItemInTx はコミット後に RemoveAttachmentFiles へ渡す保存キーを返す
+func DeleteItemInTx(tx *sql.Tx, itemID string) ([]string, error) {
+	keys, ierr := deleteItemTx(tx, itemID)
+	if ierr != nil {
+		return nil, ierr
+	}
+	return keys, nil
+}
+
+func RemoveAttachmentFiles(keys []string) {
+	removeAttachmentFiles(keys)
+}
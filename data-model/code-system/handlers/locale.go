@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// resolveLocale は lang クエリパラメータ、Accept-Language ヘッダの順にロケールを決定する
+func resolveLocale(c echo.Context) string {
+	if lang := normalizeLocale(c.QueryParam("lang")); lang != "" {
+		return lang
+	}
+
+	type candidate struct {
+		locale string
+		q      float64
+	}
+	candidates := []candidate{}
+	for _, part := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := normalizeLocale(fields[0])
+		if locale == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale: locale, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	if len(candidates) > 0 {
+		return candidates[0].locale
+	}
+
+	return models.DefaultLocale
+}
+
+// normalizeLocale は "en-US" のような言語タグを対応ロケールに変換し、未対応なら空文字を返す
+func normalizeLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if models.IsSupportedLocale(tag) {
+		return tag
+	}
+	return ""
+}
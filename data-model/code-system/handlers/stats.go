@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+func GetItemStats(c echo.Context) error {
+	stats := models.ItemStats{
+		CategoryCounts:       []models.CategoryCount{},
+		MaterialDistribution: []models.MaterialCount{},
+		MissingAttributes:    []models.ItemBasic{},
+		Timestamp:            time.Now(),
+	}
+
+	rows, err := DB.Query(`
+		SELECT 品種区分, COUNT(*)
+		FROM 品目基本属性
+		GROUP BY 品種区分
+		ORDER BY 品種区分`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to count items by category",
+		})
+	}
+	for rows.Next() {
+		var cc models.CategoryCount
+		if err := rows.Scan(&cc.CategoryType, &cc.Count); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to count items by category",
+			})
+		}
+		stats.Total += cc.Count
+		stats.CategoryCounts = append(stats.CategoryCounts, cc)
+	}
+	rows.Close()
+
+	rows, err = DB.Query(`
+		SELECT a.材質, COUNT(*)
+		FROM 品目基本属性 i
+		JOIN A品種品目属性 a ON i.品目ID = a.品目ID
+		WHERE i.品種区分 = 'A'
+		GROUP BY a.材質
+		ORDER BY COUNT(*) DESC, a.材質`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch material distribution",
+		})
+	}
+	for rows.Next() {
+		var material sql.NullString
+		var mc models.MaterialCount
+		if err := rows.Scan(&material, &mc.Count); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to fetch material distribution",
+			})
+		}
+		if material.Valid {
+			mc.Material = &material.String
+		}
+		stats.MaterialDistribution = append(stats.MaterialDistribution, mc)
+	}
+	rows.Close()
+
+	summaries := []struct {
+		query   string
+		summary *models.NumericSummary
+	}{
+		{
+			`SELECT COUNT(a.容量), MIN(a.容量), MAX(a.容量), AVG(a.容量)
+			 FROM 品目基本属性 i JOIN A品種品目属性 a ON i.品目ID = a.品目ID
+			 WHERE i.品種区分 = 'A'`,
+			&stats.Capacity,
+		},
+		{
+			`SELECT COUNT(b.内径), MIN(b.内径), MAX(b.内径), AVG(b.内径)
+			 FROM 品目基本属性 i JOIN B品種品目属性 b ON i.品目ID = b.品目ID
+			 WHERE i.品種区分 = 'B'`,
+			&stats.InnerDiameter,
+		},
+		{
+			`SELECT COUNT(b.外径), MIN(b.外径), MAX(b.外径), AVG(b.外径)
+			 FROM 品目基本属性 i JOIN B品種品目属性 b ON i.品目ID = b.品目ID
+			 WHERE i.品種区分 = 'B'`,
+			&stats.OuterDiameter,
+		},
+	}
+	for _, s := range summaries {
+		if err := scanNumericSummary(s.query, s.summary); err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to summarize item attributes",
+			})
+		}
+	}
+
+	// GetItems は両属性が NULL のサブタイプを表示しないため、行の欠落と合わせて検出する
+	rows, err = DB.Query(`
+		SELECT i.品目ID, i.品目名, i.品種区分, i.品目コード
+		FROM 品目基本属性 i
+		LEFT JOIN A品種品目属性 a ON i.品目ID = a.品目ID
+		LEFT JOIN B品種品目属性 b ON i.品目ID = b.品目ID
+		WHERE (i.品種区分 = 'A' AND a.容量 IS NULL AND a.材質 IS NULL)
+		   OR (i.品種区分 = 'B' AND b.内径 IS NULL AND b.外径 IS NULL)
+		ORDER BY i.品目ID`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch items missing attributes",
+		})
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.ItemBasic
+		if err := rows.Scan(&item.ItemID, &item.ItemName, &item.CategoryType, &item.ItemCode); err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to fetch items missing attributes",
+			})
+		}
+		stats.MissingAttributes = append(stats.MissingAttributes, item)
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+func scanNumericSummary(query string, summary *models.NumericSummary) error {
+	var min, max, avg sql.NullFloat64
+	if err := DB.QueryRow(query).Scan(&summary.Count, &min, &max, &avg); err != nil {
+		return err
+	}
+	if min.Valid {
+		summary.Min = &min.Float64
+	}
+	if max.Valid {
+		summary.Max = &max.Float64
+	}
+	if avg.Valid {
+		summary.Avg = &avg.Float64
+	}
+	return nil
+}
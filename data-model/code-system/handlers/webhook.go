@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"code-system/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+func GetWebhooks(c echo.Context) error {
+	rows, err := DB.Query("SELECT WebhookID, URL, 有効, 登録日時 FROM Webhook ORDER BY WebhookID")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch webhooks",
+		})
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.Scan(&w.WebhookID, &w.URL, &w.Active, &w.CreatedAt); err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to fetch webhooks",
+			})
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    webhooks,
+	})
+}
+
+// CreateWebhook は Webhook を登録する。シークレットはこのレスポンスでのみ返す
+func CreateWebhook(c echo.Context) error {
+	var req models.WebhookCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "URL must be an absolute http(s) URL",
+		})
+	}
+
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to generate secret",
+			})
+		}
+		req.Secret = hex.EncodeToString(b)
+	}
+
+	webhook := models.Webhook{URL: req.URL, Secret: req.Secret, Active: true}
+	err = DB.QueryRow(
+		"INSERT INTO Webhook (URL, シークレット) VALUES ($1, $2) RETURNING WebhookID, 登録日時",
+		webhook.URL, webhook.Secret,
+	).Scan(&webhook.WebhookID, &webhook.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to create webhook",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, models.Response{
+		Success: true,
+		Data:    webhook,
+	})
+}
+
+// DeleteWebhook は Webhook を無効化する。配信履歴は残す
+func DeleteWebhook(c echo.Context) error {
+	webhookID, err := strconv.ParseInt(c.Param("webhook_id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid webhook ID",
+		})
+	}
+
+	result, err := DB.Exec("UPDATE Webhook SET 有効 = FALSE WHERE WebhookID = $1 AND 有効 = TRUE", webhookID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to delete webhook",
+		})
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Webhook not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// GetDeadLetters は最大試行回数に達して配信を諦めたイベントを返す
+func GetDeadLetters(c echo.Context) error {
+	rows, err := DB.Query(`
+		SELECT d.配信ID, d.WebhookID, w.URL, d.状態, d.試行回数, d.次回試行日時,
+			   d.最終エラー, d.最終ステータスコード, d.配信日時,
+			   e.イベントID, e.イベント種別, e.品目ID, e.ペイロード, e.発生日時
+		FROM Webhook配信 d
+		JOIN Webhook w ON d.WebhookID = w.WebhookID
+		JOIN 品目イベント e ON d.イベントID = e.イベントID
+		WHERE d.状態 = 'dead'
+		ORDER BY d.配信ID DESC`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to fetch dead letters",
+		})
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var lastError sql.NullString
+		var lastStatusCode sql.NullInt64
+		var deliveredAt sql.NullTime
+		err := rows.Scan(
+			&d.DeliveryID, &d.WebhookID, &d.URL, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&lastError, &lastStatusCode, &deliveredAt,
+			&d.Event.EventID, &d.Event.EventType, &d.Event.ItemID, &d.Event.Payload, &d.Event.OccurredAt,
+		)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, models.Response{
+				Success: false,
+				Error:   "Failed to fetch dead letters",
+			})
+		}
+		if lastError.Valid {
+			d.LastError = &lastError.String
+		}
+		if lastStatusCode.Valid {
+			code := int(lastStatusCode.Int64)
+			d.LastStatusCode = &code
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    deliveries,
+	})
+}
+
+// RetryDeadLetter は配信不能になった配信を試行回数をリセットして再送キューに戻す
+func RetryDeadLetter(c echo.Context) error {
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error:   "Invalid delivery ID",
+		})
+	}
+
+	result, err := DB.Exec(`
+		UPDATE Webhook配信
+		SET 状態 = 'pending', 試行回数 = 0, 次回試行日時 = CURRENT_TIMESTAMP
+		WHERE 配信ID = $1 AND 状態 = 'dead'`, deliveryID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error:   "Failed to requeue delivery",
+		})
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, models.Response{
+			Success: false,
+			Error:   "Dead letter not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: "Delivery requeued successfully",
+	})
+}
@@ -22,7 +22,66 @@ CREATE TABLE IF NOT EXISTS B品種品目属性 (
     FOREIGN KEY (品目ID) REFERENCES 品目基本属性(品目ID) ON DELETE CASCADE
 );
 
+-- 品目名称テーブル（既定ロケール ja の品目名は品目基本属性に保持）
+CREATE TABLE IF NOT EXISTS 品目名称 (
+    品目ID VARCHAR(10) NOT NULL,
+    言語コード VARCHAR(5) NOT NULL CHECK (言語コード IN ('en', 'zh')),
+    品目名 VARCHAR(100) NOT NULL,
+    PRIMARY KEY (品目ID, 言語コード),
+    FOREIGN KEY (品目ID) REFERENCES 品目基本属性(品目ID) ON DELETE CASCADE
+);
+
+-- 品目添付ファイルテーブル
+CREATE TABLE IF NOT EXISTS 品目添付ファイル (
+    添付ファイルID BIGSERIAL PRIMARY KEY,
+    品目ID VARCHAR(10) NOT NULL,
+    ファイル名 VARCHAR(255) NOT NULL,
+    ファイル種別 VARCHAR(20) NOT NULL CHECK (ファイル種別 IN ('drawing', 'spec_sheet', 'image', 'other')),
+    コンテンツタイプ VARCHAR(100) NOT NULL,
+    ファイルサイズ BIGINT NOT NULL,
+    チェックサム CHAR(64) NOT NULL,
+    登録者 VARCHAR(100) NOT NULL DEFAULT '',
+    保存キー VARCHAR(255) NOT NULL UNIQUE,
+    登録日時 TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (品目ID) REFERENCES 品目基本属性(品目ID) ON DELETE CASCADE
+);
+
+-- 品目イベントテーブル（トランザクショナルアウトボックス）
+CREATE TABLE IF NOT EXISTS 品目イベント (
+    イベントID BIGSERIAL PRIMARY KEY,
+    イベント種別 VARCHAR(20) NOT NULL CHECK (イベント種別 IN ('item.created', 'item.updated', 'item.deleted')),
+    品目ID VARCHAR(10) NOT NULL,
+    ペイロード JSONB NOT NULL,
+    発生日時 TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhookテーブル
+CREATE TABLE IF NOT EXISTS Webhook (
+    WebhookID BIGSERIAL PRIMARY KEY,
+    URL VARCHAR(2048) NOT NULL,
+    シークレット VARCHAR(255) NOT NULL,
+    有効 BOOLEAN NOT NULL DEFAULT TRUE,
+    登録日時 TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhook配信テーブル
+CREATE TABLE IF NOT EXISTS Webhook配信 (
+    配信ID BIGSERIAL PRIMARY KEY,
+    イベントID BIGINT NOT NULL,
+    WebhookID BIGINT NOT NULL,
+    状態 VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (状態 IN ('pending', 'delivered', 'dead')),
+    試行回数 INTEGER NOT NULL DEFAULT 0,
+    次回試行日時 TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    最終エラー TEXT,
+    最終ステータスコード INTEGER,
+    配信日時 TIMESTAMP,
+    FOREIGN KEY (イベントID) REFERENCES 品目イベント(イベントID) ON DELETE CASCADE,
+    FOREIGN KEY (WebhookID) REFERENCES Webhook(WebhookID) ON DELETE CASCADE
+);
+
 -- インデックスの作成
+CREATE INDEX idx_webhook配信_保留 ON Webhook配信(次回試行日時) WHERE 状態 = 'pending';
+CREATE INDEX idx_添付ファイル_品目ID ON 品目添付ファイル(品目ID);
 CREATE INDEX idx_品目コード ON 品目基本属性(品目コード);
 CREATE INDEX idx_品種区分 ON 品目基本属性(品種区分);
 
@@ -47,4 +106,15 @@ INSERT INTO 品目基本属性 (品目ID, 品目名, 品種区分, 品目コー
 INSERT INTO B品種品目属性 (品目ID, 内径, 外径) VALUES
 ('B001', 15.00, 20.00),
 ('B002', 44.00, 50.00),
-('B003', 13.00, 15.00);
\ No newline at end of file
+('B003', 13.00, 15.00);
+
+-- 品目名称のデータ
+INSERT INTO 品目名称 (品目ID, 言語コード, 品目名) VALUES
+('A001', 'en', 'Plastic Bottle 500ml'),
+('A001', 'zh', '塑料瓶500ml'),
+('A002', 'en', 'Glass Bottle 1000ml'),
+('A002', 'zh', '玻璃瓶1000ml'),
+('A003', 'en', 'Aluminum Can 350ml'),
+('B001', 'en', 'Stainless Steel Pipe 20mm'),
+('B002', 'en', 'PVC Pipe 50mm'),
+('B003', 'en', 'Copper Tube 15mm');
\ No newline at end of file
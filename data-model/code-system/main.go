@@ -1,20 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
+	"code-system/cache"
+	"code-system/events"
 	"code-system/handlers"
+	"code-system/openapi"
+	"code-system/storage"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/lib/pq"
 )
 
-func initDB() (*sql.DB, error) {
+func dataSourceName() string {
 	dbHost := os.Getenv("DB_HOST")
 	if dbHost == "" {
 		dbHost = "localhost"
@@ -40,8 +46,12 @@ func initDB() (*sql.DB, error) {
 		dbName = "code_system"
 	}
 
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, dbPort, dbUser, dbPassword, dbName)
+}
+
+func initDB() (*sql.DB, error) {
+	psqlInfo := dataSourceName()
 
 	db, err := sql.Open("postgres", psqlInfo)
 	if err != nil {
@@ -74,6 +84,28 @@ func main() {
 
 	handlers.SetDB(db)
 
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		attachmentDir = "attachments"
+	}
+	attachmentStorage, err := storage.NewLocalStorage(attachmentDir)
+	if err != nil {
+		log.Fatal("Failed to initialize attachment storage:", err)
+	}
+	handlers.SetStorage(attachmentStorage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go events.NewDispatcher(db, events.DefaultDispatcherConfig()).Run(ctx)
+
+	itemCache := cache.NewItemCache(envInt("ITEM_CACHE_SIZE", 1000), time.Duration(envInt("ITEM_CACHE_TTL_SECONDS", 300))*time.Second)
+	if os.Getenv("ITEM_CACHE_NOTIFY") == "true" {
+		if err := itemCache.Listen(ctx, dataSourceName()); err != nil {
+			log.Fatal("Failed to listen for item cache invalidation:", err)
+		}
+	}
+	handlers.SetItemCache(itemCache)
+
 	e := echo.New()
 
 	e.Use(middleware.Logger())
@@ -82,13 +114,47 @@ func main() {
 
 	e.Static("/", "public")
 
-	api := e.Group("/api")
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatal("Failed to load OpenAPI document:", err)
+	}
+	validator, err := openapi.ValidateRequest(spec)
+	if err != nil {
+		log.Fatal("Failed to initialize request validator:", err)
+	}
+
+	e.GET("/graphql", handlers.GraphQL)
+	e.POST("/graphql", handlers.GraphQL)
+
+	// 検証ミドルウェアは本文をすべて読み込むため、その前に本文の大きさを制限する（添付ファイルの上限20MBにフォームの分を加えたもの）
+	api := e.Group("/api", middleware.BodyLimit("21M"), validator)
 	{
+		api.GET("/openapi.json", openapi.Handler)
+
 		api.GET("/items", handlers.GetItems)
+		api.GET("/items/stats", handlers.GetItemStats)
+		api.GET("/items/integrity", handlers.CheckItemIntegrity)
+		api.POST("/items/integrity/repair", handlers.RepairItemIntegrity)
+		api.POST("/items/batch-get", handlers.BatchGetItems)
+		api.POST("/items/batch", handlers.BatchWriteItems)
+		api.GET("/items/by-code/:code", handlers.GetItemByCode)
 		api.GET("/items/:id", handlers.GetItem)
 		api.POST("/items", handlers.CreateItem)
 		api.PUT("/items/:id", handlers.UpdateItem)
 		api.DELETE("/items/:id", handlers.DeleteItem)
+
+		api.GET("/items/:id/attachments", handlers.GetAttachments)
+		api.POST("/items/:id/attachments", handlers.UploadAttachment)
+		api.GET("/items/:id/attachments/:attachment_id", handlers.DownloadAttachment)
+		api.DELETE("/items/:id/attachments/:attachment_id", handlers.DeleteAttachment)
+
+		api.GET("/cache/stats", handlers.GetCacheStats)
+
+		api.GET("/webhooks", handlers.GetWebhooks)
+		api.POST("/webhooks", handlers.CreateWebhook)
+		api.DELETE("/webhooks/:webhook_id", handlers.DeleteWebhook)
+		api.GET("/webhooks/dead-letters", handlers.GetDeadLetters)
+		api.POST("/webhooks/dead-letters/:delivery_id/retry", handlers.RetryDeadLetter)
 	}
 
 	port := os.Getenv("PORT")
@@ -100,4 +166,12 @@ func main() {
 	if err := e.Start(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
-}
\ No newline at end of file
+}
+
+func envInt(key string, defaultValue int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	AttachmentID   int64     `json:"attachment_id" db:"添付ファイルid"`
+	ItemID         string    `json:"item_id" db:"品目id"`
+	FileName       string    `json:"file_name" db:"ファイル名"`
+	AttachmentType string    `json:"attachment_type" db:"ファイル種別"`
+	ContentType    string    `json:"content_type" db:"コンテンツタイプ"`
+	Size           int64     `json:"size" db:"ファイルサイズ"`
+	Checksum       string    `json:"checksum" db:"チェックサム"`
+	UploadedBy     string    `json:"uploaded_by" db:"登録者"`
+	StorageKey     string    `json:"-" db:"保存キー"`
+	UploadedAt     time.Time `json:"uploaded_at" db:"登録日時"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "encoding/json"
+
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+type ItemBatchGetRequest struct {
+	ItemIDs   []string `json:"item_ids,omitempty"`
+	ItemCodes []string `json:"item_codes,omitempty"`
+}
+
+type ItemBatchGetResponse struct {
+	Items             []ItemWithDetails `json:"items"`
+	NotFoundItemIDs   []string          `json:"not_found_item_ids"`
+	NotFoundItemCodes []string          `json:"not_found_item_codes"`
+}
+
+// ItemBatchOperation の Data は Op に応じて ItemCreateRequest / ItemUpdateRequest として解釈する
+type ItemBatchOperation struct {
+	Op     string          `json:"op"`
+	ItemID string          `json:"item_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+type ItemBatchRequest struct {
+	Mode       string               `json:"mode"`
+	Operations []ItemBatchOperation `json:"operations"`
+}
+
+type ItemBatchResult struct {
+	Index   int              `json:"index"`
+	Op      string           `json:"op"`
+	ItemID  string           `json:"item_id"`
+	Success bool             `json:"success"`
+	Status  int              `json:"status"`
+	Error   string           `json:"error,omitempty"`
+	Item    *ItemWithDetails `json:"item,omitempty"`
+}
+
+type ItemBatchResponse struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []ItemBatchResult `json:"results"`
+}
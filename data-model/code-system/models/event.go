@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventItemCreated = "item.created"
+	EventItemUpdated = "item.updated"
+	EventItemDeleted = "item.deleted"
+)
+
+type ItemEvent struct {
+	EventID    int64           `json:"event_id" db:"イベントid"`
+	EventType  string          `json:"event_type" db:"イベント種別"`
+	ItemID     string          `json:"item_id" db:"品目id"`
+	Payload    json.RawMessage `json:"payload" db:"ペイロード"`
+	OccurredAt time.Time       `json:"occurred_at" db:"発生日時"`
+}
+
+type Webhook struct {
+	WebhookID int64     `json:"webhook_id" db:"webhookid"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"secret,omitempty" db:"シークレット"`
+	Active    bool      `json:"active" db:"有効"`
+	CreatedAt time.Time `json:"created_at" db:"登録日時"`
+}
+
+type WebhookCreateRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID     int64      `json:"delivery_id" db:"配信id"`
+	WebhookID      int64      `json:"webhook_id" db:"webhookid"`
+	URL            string     `json:"url"`
+	Status         string     `json:"status" db:"状態"`
+	Attempts       int        `json:"attempts" db:"試行回数"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"次回試行日時"`
+	LastError      *string    `json:"last_error" db:"最終エラー"`
+	LastStatusCode *int       `json:"last_status_code" db:"最終ステータスコード"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"配信日時"`
+	Event          ItemEvent  `json:"event"`
+}
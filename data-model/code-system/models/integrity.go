@@ -0,0 +1,36 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// 品目コードの形式。英大文字・数字のセグメントをハイフンでつないだもの（例: PBOT-500）
+var ItemCodePattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
+const (
+	IssueOrphanedSubtype   = "orphaned_subtype"
+	IssueMissingSubtype    = "missing_subtype"
+	IssueMismatchedSubtype = "mismatched_subtype"
+	IssueInvalidItemCode   = "invalid_item_code"
+)
+
+type IntegrityIssue struct {
+	Type         string  `json:"type"`
+	ItemID       string  `json:"item_id"`
+	CategoryType *string `json:"category_type"`
+	ItemCode     *string `json:"item_code"`
+	SubtypeTable string  `json:"subtype_table,omitempty"`
+	Detail       string  `json:"detail"`
+	Repair       string  `json:"repair,omitempty"`
+	Repaired     bool    `json:"repaired"`
+}
+
+type IntegrityReport struct {
+	Checked     int              `json:"checked"`
+	IssueCounts map[string]int   `json:"issue_counts"`
+	Issues      []IntegrityIssue `json:"issues"`
+	RepairMode  bool             `json:"repair_mode"`
+	Repaired    int              `json:"repaired"`
+	Timestamp   time.Time        `json:"timestamp"`
+}
@@ -26,8 +26,10 @@ type ItemTypeB struct {
 
 type ItemWithDetails struct {
 	ItemBasic
-	TypeA *ItemTypeA `json:"type_a,omitempty"`
-	TypeB *ItemTypeB `json:"type_b,omitempty"`
+	Locale    string            `json:"locale"`
+	ItemNames map[string]string `json:"item_names,omitempty"`
+	TypeA     *ItemTypeA        `json:"type_a,omitempty"`
+	TypeB     *ItemTypeB        `json:"type_b,omitempty"`
 }
 
 type ItemCreateRequest struct {
@@ -35,6 +37,7 @@ type ItemCreateRequest struct {
 	ItemName     string   `json:"item_name"`
 	CategoryType string   `json:"category_type"`
 	ItemCode     string   `json:"item_code"`
+	ItemNames    map[string]string `json:"item_names,omitempty"`
 	Capacity     *float64 `json:"capacity,omitempty"`
 	Material     *string  `json:"material,omitempty"`
 	InnerDiameter *float64 `json:"inner_diameter,omitempty"`
@@ -44,6 +47,7 @@ type ItemCreateRequest struct {
 type ItemUpdateRequest struct {
 	ItemName     *string  `json:"item_name,omitempty"`
 	ItemCode     *string  `json:"item_code,omitempty"`
+	ItemNames    map[string]string `json:"item_names,omitempty"`
 	Capacity     *float64 `json:"capacity,omitempty"`
 	Material     *string  `json:"material,omitempty"`
 	InnerDiameter *float64 `json:"inner_diameter,omitempty"`
@@ -0,0 +1,15 @@
+package models
+
+// DefaultLocale の品目名は品目基本属性.品目名 に保持し、他のロケールは品目名称に保持する
+const DefaultLocale = "ja"
+
+var SupportedLocales = []string{"ja", "en", "zh"}
+
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type CategoryCount struct {
+	CategoryType string `json:"category_type"`
+	Count        int    `json:"count"`
+}
+
+type MaterialCount struct {
+	Material *string `json:"material"`
+	Count    int     `json:"count"`
+}
+
+type NumericSummary struct {
+	Count int      `json:"count"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Avg   *float64 `json:"avg"`
+}
+
+type ItemStats struct {
+	Total                int             `json:"total"`
+	CategoryCounts       []CategoryCount `json:"category_counts"`
+	MaterialDistribution []MaterialCount `json:"material_distribution"`
+	Capacity             NumericSummary  `json:"capacity"`
+	InnerDiameter        NumericSummary  `json:"inner_diameter"`
+	OuterDiameter        NumericSummary  `json:"outer_diameter"`
+	MissingAttributes    []ItemBasic     `json:"missing_attributes"`
+	Timestamp            time.Time       `json:"timestamp"`
+}
@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"code-system/models"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+// Load は埋め込まれた OpenAPI ドキュメントを読み込み、モデルとの整合性を検証する
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(specJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+	}
+	if err := CheckModels(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// CheckModels はリクエストモデルの JSON フィールドとスキーマのプロパティが一致することを確認する
+func CheckModels(doc *openapi3.T) error {
+	targets := map[string]interface{}{
+		"ItemCreateRequest":    models.ItemCreateRequest{},
+		"ItemUpdateRequest":    models.ItemUpdateRequest{},
+		"ItemBatchGetRequest":  models.ItemBatchGetRequest{},
+		"ItemBatchRequest":     models.ItemBatchRequest{},
+		"ItemBatchOperation":   models.ItemBatchOperation{},
+		"WebhookCreateRequest": models.WebhookCreateRequest{},
+	}
+
+	var problems []string
+	for name, model := range targets {
+		schemaRef, ok := doc.Components.Schemas[name]
+		if !ok || schemaRef.Value == nil {
+			problems = append(problems, fmt.Sprintf("schema %s is missing", name))
+			continue
+		}
+
+		fields := jsonFields(reflect.TypeOf(model))
+		for field := range fields {
+			if _, ok := schemaRef.Value.Properties[field]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is not documented", name, field))
+			}
+		}
+		for property := range schemaRef.Value.Properties {
+			if !fields[property] {
+				problems = append(problems, fmt.Sprintf("%s.%s is documented but not in models", name, property))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI document is out of sync with models: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for name := range jsonFields(f.Type) {
+				fields[name] = true
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// Handler は OpenAPI ドキュメントを JSON で返す
+func Handler(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, specJSON)
+}
+
+// ValidateRequest はドキュメントに定義されたリクエストを検証する。未定義のパスはそのまま通す
+func ValidateRequest(doc *openapi3.T) (echo.MiddlewareFunc, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI router: %v", err)
+	}
+
+	// エラーメッセージにスキーマ全体を含めない
+	openapi3.SchemaErrorDetailsDisabled = true
+	registerDecoders.Do(func() {
+		openapi3filter.RegisterBodyDecoder("multipart/form-data",
+			binaryPartDecoder(openapi3filter.RegisteredBodyDecoder("multipart/form-data")))
+	})
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         true,
+	}
+	// multipart/form-data は既定値を補ったボディに書き戻せないため、既定値の補完はハンドラーに任せる
+	multipartOptions := *options
+	multipartOptions.SkipSettingDefaults = true
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route, pathParams, err := router.FindRoute(req)
+			if errors.Is(err, routers.ErrPathNotFound) || errors.Is(err, routers.ErrMethodNotAllowed) {
+				return next(c)
+			} else if err != nil {
+				return c.JSON(http.StatusBadRequest, models.Response{
+					Success: false,
+					Error:   "Request validation failed: " + err.Error(),
+				})
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+				input.Options = &multipartOptions
+			}
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				return c.JSON(http.StatusBadRequest, models.Response{
+					Success: false,
+					Error:   "Request validation failed: " + err.Error(),
+				})
+			}
+
+			return next(c)
+		}
+	}, nil
+}
+
+var registerDecoders sync.Once
+
+// binaryPartDecoder は multipart/form-data のうち format: binary のパートを、パートのコンテンツタイプによらずファイルとして検証する
+// kin-openapi はパートごとのコンテンツタイプでデコーダーを選ぶため、application/pdf や image/png などのファイルが未対応として拒否される
+func binaryPartDecoder(decode openapi3filter.BodyDecoder) openapi3filter.BodyDecoder {
+	return func(body io.Reader, header http.Header, schema *openapi3.SchemaRef, encFn openapi3filter.EncodingFn) (interface{}, error) {
+		_, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			partHeader := textproto.MIMEHeader{}
+			for key, values := range part.Header {
+				partHeader[key] = values
+			}
+			if isBinaryProperty(schema, part.FormName()) {
+				partHeader.Set("Content-Type", "application/octet-stream")
+			}
+			w, err := writer.CreatePart(partHeader)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(w, part); err != nil {
+				return nil, err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		rewritten := header.Clone()
+		rewritten.Set("Content-Type", writer.FormDataContentType())
+		return decode(&buf, rewritten, schema, encFn)
+	}
+}
+
+// isBinaryProperty はプロパティが type: string, format: binary（またはその配列）かどうかを返す
+func isBinaryProperty(schema *openapi3.SchemaRef, name string) bool {
+	if schema == nil || schema.Value == nil {
+		return false
+	}
+	schemas := []*openapi3.SchemaRef{schema}
+	schemas = append(schemas, schema.Value.AllOf...)
+	for _, s := range schemas {
+		if s.Value == nil {
+			continue
+		}
+		property, ok := s.Value.Properties[name]
+		if !ok || property.Value == nil {
+			continue
+		}
+		if property.Value.Type == "array" && property.Value.Items != nil {
+			property = property.Value.Items
+		}
+		return property.Value != nil && property.Value.Type == "string" && property.Value.Format == "binary"
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newValidatedServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	validator, err := ValidateRequest(doc)
+	if err != nil {
+		t.Fatalf("ValidateRequest: %v", err)
+	}
+
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e := echo.New()
+	api := e.Group("/api", validator)
+	api.GET("/items", ok)
+	api.POST("/items/:id/attachments", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+	api.GET("/cache/stats", ok)
+	api.GET("/webhooks", ok)
+	api.POST("/webhooks", ok)
+	api.DELETE("/webhooks/:webhook_id", ok)
+	api.GET("/webhooks/dead-letters", ok)
+	api.POST("/webhooks/dead-letters/:delivery_id/retry", ok)
+	return e
+}
+
+type formPart struct {
+	name        string
+	fileName    string
+	contentType string
+	body        []byte
+}
+
+func multipartRequest(t *testing.T, parts []formPart) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		if p.fileName != "" {
+			header.Set("Content-Disposition", `form-data; name="`+p.name+`"; filename="`+p.fileName+`"`)
+		} else {
+			header.Set("Content-Disposition", `form-data; name="`+p.name+`"`)
+		}
+		if p.contentType != "" {
+			header.Set("Content-Type", p.contentType)
+		}
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			t.Fatalf("CreatePart: %v", err)
+		}
+		if _, err := w.Write(p.body); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/items/ITEM001/attachments", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestValidateRequestAttachmentUpload(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF\n")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+
+	tests := []struct {
+		name   string
+		parts  []formPart
+		status int
+	}{
+		{
+			name: "pdf",
+			parts: []formPart{
+				{name: "file", fileName: "spec.pdf", contentType: "application/pdf", body: pdf},
+				{name: "attachment_type", body: []byte("spec_sheet")},
+				{name: "uploaded_by", body: []byte("tanaka")},
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name: "png",
+			parts: []formPart{
+				{name: "file", fileName: "photo.png", contentType: "image/png", body: png},
+				{name: "attachment_type", body: []byte("image")},
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name: "octet-stream",
+			parts: []formPart{
+				{name: "file", fileName: "drawing.dwg", contentType: "application/octet-stream", body: []byte("AC1032")},
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name: "invalid attachment type",
+			parts: []formPart{
+				{name: "file", fileName: "spec.pdf", contentType: "application/pdf", body: pdf},
+				{name: "attachment_type", body: []byte("unknown")},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "missing file",
+			parts: []formPart{
+				{name: "attachment_type", body: []byte("image")},
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	e := newValidatedServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, multipartRequest(t, tt.parts))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestValidateRequestLenientParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		// ロケールは地域付きの言語タグ・大文字・未対応の言語も受け付け、ハンドラーで解釈する
+		{"lang", http.MethodGet, "/api/items?lang=en", "", http.StatusOK},
+		{"lang with region", http.MethodGet, "/api/items?lang=en-US", "", http.StatusOK},
+		{"lang upper case", http.MethodGet, "/api/items?lang=EN", "", http.StatusOK},
+		{"unsupported lang", http.MethodGet, "/api/items?lang=fr", "", http.StatusOK},
+		{"malformed lang", http.MethodGet, "/api/items?lang=en%20US", "", http.StatusBadRequest},
+		// 範囲外のページ指定はハンドラーで既定値として扱う
+		{"page zero", http.MethodGet, "/api/items?page=0", "", http.StatusOK},
+		{"page size over limit", http.MethodGet, "/api/items?page_size=1000", "", http.StatusOK},
+		{"page not a number", http.MethodGet, "/api/items?page=first", "", http.StatusBadRequest},
+		// Webhook とキャッシュ統計もドキュメントに照らして検証する
+		{"cache stats", http.MethodGet, "/api/cache/stats", "", http.StatusOK},
+		{"webhooks", http.MethodGet, "/api/webhooks", "", http.StatusOK},
+		{"create webhook", http.MethodPost, "/api/webhooks", `{"url":"https://example.com/hook"}`, http.StatusOK},
+		{"create webhook without url", http.MethodPost, "/api/webhooks", `{"secret":"s"}`, http.StatusBadRequest},
+		{"delete webhook", http.MethodDelete, "/api/webhooks/1", "", http.StatusOK},
+		{"delete webhook with invalid id", http.MethodDelete, "/api/webhooks/abc", "", http.StatusBadRequest},
+		{"dead letters", http.MethodGet, "/api/webhooks/dead-letters", "", http.StatusOK},
+		{"retry dead letter", http.MethodPost, "/api/webhooks/dead-letters/1/retry", "", http.StatusOK},
+		{"retry dead letter with invalid id", http.MethodPost, "/api/webhooks/dead-letters/abc/retry", "", http.StatusBadRequest},
+	}
+
+	e := newValidatedServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
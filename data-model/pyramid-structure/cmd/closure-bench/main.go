@@ -0,0 +1,311 @@
+// closure-bench は組織階層閉包テーブルを使った階層の取得と、
+// 組織属性から失効年月日を都度計算する従来のクエリの性能を比較する
+//
+// 接続先はアプリケーションと同じ環境変数（DB_HOST など）で指定する
+//
+//	go run ./cmd/closure-bench -departments 10000
+//
+// 生成した部門（部門IDが BENCH_ で始まる）は終了時に削除する（-keep で残す）
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"org-hierarchy/models"
+
+	"github.com/lib/pq"
+)
+
+const idPrefix = "BENCH_"
+
+// legacyQuery 閉包テーブル導入前の階層取得クエリ（部門名の結合を除く）
+const legacyQuery = `
+	WITH latest_attrs AS (
+		SELECT DISTINCT ON (department_id)
+			department_id,
+			effective_date,
+			parent_department_id,
+			is_abolished
+		FROM organization_attributes
+		WHERE effective_date <= $1
+		ORDER BY department_id, effective_date DESC
+	)
+	SELECT
+		la.department_id,
+		la.effective_date,
+		la.parent_department_id,
+		(
+			SELECT MIN(oa2.effective_date) - INTERVAL '1 day'
+			FROM organization_attributes oa2
+			WHERE oa2.department_id = la.department_id
+			AND oa2.effective_date > la.effective_date
+		) AS expiration_date
+	FROM latest_attrs la
+	WHERE NOT la.is_abolished
+	ORDER BY la.department_id`
+
+// closureQuery 閉包テーブルを使った階層取得クエリ（部門名の結合を除く）
+const closureQuery = `
+	SELECT
+		c.department_id,
+		c.valid_from,
+		oa.parent_department_id,
+		c.valid_to
+	FROM organization_closure c
+	JOIN organization_attributes oa ON oa.department_id = c.department_id AND oa.effective_date = c.valid_from
+	WHERE c.depth = 0 AND c.valid_from <= $1 AND (c.valid_to IS NULL OR c.valid_to >= $1)
+	ORDER BY c.department_id`
+
+func main() {
+	departments := flag.Int("departments", 10000, "生成する部門数")
+	roots := flag.Int("roots", 10, "最上位の部門数")
+	moveRate := flag.Float64("move-rate", 0.3, "発足後に上位部門が変わる部門の割合")
+	dates := flag.Int("dates", 12, "階層を取得する基準日の数")
+	seed := flag.Int64("seed", 1, "乱数のシード")
+	keep := flag.Bool("keep", false, "生成した部門を削除せずに残す")
+	flag.Parse()
+
+	db, err := connectDB()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	if err := cleanup(db); err != nil {
+		log.Fatal("Failed to remove previous benchmark data:", err)
+	}
+	if !*keep {
+		defer func() {
+			if err := cleanup(db); err != nil {
+				log.Print("Failed to remove benchmark data:", err)
+			}
+		}()
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+
+	start := time.Now()
+	records, err := generate(db, rng, *departments, *roots, *moveRate)
+	if err != nil {
+		log.Fatal("Failed to generate data:", err)
+	}
+	fmt.Printf("生成: 部門 %d件 / 組織属性 %d件 (%v)\n", *departments, records, time.Since(start))
+
+	repo := models.NewOrganizationAttributeRepository(db)
+
+	start = time.Now()
+	if err := repo.RebuildClosure(); err != nil {
+		log.Fatal("Failed to rebuild closure:", err)
+	}
+	var closureRows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM organization_closure`).Scan(&closureRows); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("閉包テーブルの全件再構築: %d行 (%v)\n", closureRows, time.Since(start))
+
+	if _, err := db.Exec(`ANALYZE organization_attributes; ANALYZE organization_closure`); err != nil {
+		log.Fatal(err)
+	}
+
+	// 基準日は生成したデータの期間（2020年〜2024年）から均等に選ぶ
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := to.Sub(from) / time.Duration(*dates)
+
+	var legacyTotal, closureTotal, repoTotal time.Duration
+	fmt.Printf("\n%-12s %8s %12s %12s %12s\n", "基準日", "部門数", "従来", "閉包", "階層API")
+	for i := 0; i < *dates; i++ {
+		date := from.Add(step * time.Duration(i))
+
+		legacyCount, legacyTime, err := timeQuery(db, legacyQuery, date)
+		if err != nil {
+			log.Fatal("Legacy query failed:", err)
+		}
+		closureCount, closureTime, err := timeQuery(db, closureQuery, date)
+		if err != nil {
+			log.Fatal("Closure query failed:", err)
+		}
+
+		start := time.Now()
+		attrs, err := repo.GetHierarchyByDate(date)
+		if err != nil {
+			log.Fatal("GetHierarchyByDate failed:", err)
+		}
+		repoTime := time.Since(start)
+
+		if legacyCount != closureCount || legacyCount != len(attrs) {
+			log.Fatalf("Result mismatch on %s: legacy=%d closure=%d repository=%d",
+				date.Format("2006-01-02"), legacyCount, closureCount, len(attrs))
+		}
+
+		legacyTotal += legacyTime
+		closureTotal += closureTime
+		repoTotal += repoTime
+		fmt.Printf("%-12s %8d %12v %12v %12v\n", date.Format("2006-01-02"), legacyCount,
+			legacyTime.Round(time.Microsecond), closureTime.Round(time.Microsecond), repoTime.Round(time.Microsecond))
+	}
+
+	n := time.Duration(*dates)
+	fmt.Printf("%-12s %8s %12v %12v %12v\n", "平均", "",
+		(legacyTotal / n).Round(time.Microsecond), (closureTotal / n).Round(time.Microsecond), (repoTotal / n).Round(time.Microsecond))
+
+	// 書き込み時の閉包テーブル更新のコスト（配下の部門が多い、最初に生成した最上位以外の部門のレコードを更新する）
+	var target models.OrganizationAttribute
+	err = db.QueryRow(`
+		SELECT department_id, effective_date, parent_department_id
+		FROM organization_attributes
+		WHERE department_id = $1
+		ORDER BY effective_date
+		LIMIT 1`, benchID(*roots)).
+		Scan(&target.DepartmentID, &target.EffectiveDate, &target.ParentDepartmentID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var subtree int
+	err = db.QueryRow(`SELECT COUNT(DISTINCT department_id) - 1 FROM organization_closure WHERE ancestor_id = $1`,
+		target.DepartmentID).Scan(&subtree)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	start = time.Now()
+	if err := repo.Update(&target); err != nil {
+		log.Fatal("Update failed:", err)
+	}
+	fmt.Printf("\n組織属性の更新（%s、いずれかの時点で配下にあった部門 %d件の閉包テーブルを更新）: %v\n",
+		target.DepartmentID, subtree, time.Since(start))
+}
+
+// generate 部門と組織属性を生成する
+// 上位部門は常に自部門より前に生成した部門から選ぶため、どの時点でも循環は生じない
+func generate(db *sql.DB, rng *rand.Rand, departments, roots int, moveRate float64) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyIn("departments", "department_id"))
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < departments; i++ {
+		if _, err := stmt.Exec(benchID(i)); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	stmt, err = tx.Prepare(pq.CopyIn("organization_attributes", "department_id", "effective_date", "parent_department_id"))
+	if err != nil {
+		return 0, err
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := 0
+	for i := 0; i < departments; i++ {
+		var parent interface{}
+		if i >= roots {
+			parent = benchID(rng.Intn(i))
+		}
+		if _, err := stmt.Exec(benchID(i), start, parent); err != nil {
+			return 0, err
+		}
+		records++
+
+		if i >= roots && rng.Float64() < moveRate {
+			moved := start.AddDate(1, 0, rng.Intn(4*365))
+			if _, err := stmt.Exec(benchID(i), moved, benchID(rng.Intn(i))); err != nil {
+				return 0, err
+			}
+			records++
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	return records, tx.Commit()
+}
+
+func benchID(i int) string {
+	return fmt.Sprintf("%s%05d", idPrefix, i)
+}
+
+// timeQuery クエリを実行してすべての行を読み終えるまでの時間を計る
+func timeQuery(db *sql.DB, query string, date time.Time) (int, time.Duration, error) {
+	start := time.Now()
+	rows, err := db.Query(query, date)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	return count, time.Since(start), rows.Err()
+}
+
+// cleanup 生成した部門を削除する（組織属性と閉包テーブルの行は連鎖して削除される）
+func cleanup(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM organization_attributes WHERE department_id LIKE $1`, idPrefix+"%"); err != nil {
+		return err
+	}
+	// 記録時点の履歴は外部キーで部門とつながっていないため、削除時にトリガーが残した履歴も消す
+	if _, err := tx.Exec(`DELETE FROM organization_attribute_versions WHERE department_id LIKE $1`, idPrefix+"%"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM departments WHERE department_id LIKE $1`, idPrefix+"%"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func connectDB() (*sql.DB, error) {
+	host := os.Getenv("DB_HOST")
+	port := os.Getenv("DB_PORT")
+	user := os.Getenv("DB_USER")
+	password := os.Getenv("DB_PASSWORD")
+	dbname := os.Getenv("DB_NAME")
+
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "5432"
+	}
+
+	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host, port, user, password, dbname)
+
+	db, err := sql.Open("postgres", psqlInfo)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AssignmentHandler struct {
+	repo *models.AssignmentRepository
+}
+
+func NewAssignmentHandler(repo *models.AssignmentRepository) *AssignmentHandler {
+	return &AssignmentHandler{repo: repo}
+}
+
+// assignmentInput 所属の作成・更新のリクエスト
+type assignmentInput struct {
+	EmployeeID     string  `json:"employee_id"`
+	DepartmentID   string  `json:"department_id"`
+	EffectiveDate  string  `json:"effective_date"`
+	ExpirationDate *string `json:"expiration_date"`
+	Position       *string `json:"position"`
+	Primary        *bool   `json:"primary"`
+}
+
+// toAssignment 入力を検証して所属に変換する（主務・兼務の指定を省略した場合は主務）
+func (in assignmentInput) toAssignment() (*models.Assignment, string) {
+	if in.DepartmentID == "" || in.EffectiveDate == "" {
+		return nil, "Department ID and effective date are required"
+	}
+	
+	effectiveDate, err := time.Parse("2006-01-02", in.EffectiveDate)
+	if err != nil {
+		return nil, "Invalid date format. Use YYYY-MM-DD"
+	}
+	
+	a := &models.Assignment{
+		EmployeeID:    in.EmployeeID,
+		DepartmentID:  in.DepartmentID,
+		EffectiveDate: effectiveDate,
+		Position:      in.Position,
+		Primary:       true,
+	}
+	
+	if in.ExpirationDate != nil && *in.ExpirationDate != "" {
+		expirationDate, err := time.Parse("2006-01-02", *in.ExpirationDate)
+		if err != nil {
+			return nil, "Invalid date format. Use YYYY-MM-DD"
+		}
+		a.ExpirationDate = &expirationDate
+	}
+	
+	if in.Primary != nil {
+		a.Primary = *in.Primary
+	}
+	
+	return a, ""
+}
+
+func (h *AssignmentHandler) GetByID(c echo.Context) error {
+	assignmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignment ID"})
+	}
+	
+	assignment, err := h.repo.GetByID(assignmentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if assignment == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Assignment not found"})
+	}
+	
+	return c.JSON(http.StatusOK, assignment)
+}
+
+func (h *AssignmentHandler) Create(c echo.Context) error {
+	var input assignmentInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if input.EmployeeID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Employee ID is required"})
+	}
+	
+	assignment, message := input.toAssignment()
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+	}
+	
+	if err := h.repo.Create(assignment); err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	// 作成後、社員名・部門名を含む完全なデータを取得
+	created, err := h.repo.GetByID(assignment.AssignmentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h *AssignmentHandler) Update(c echo.Context) error {
+	assignmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignment ID"})
+	}
+	
+	var input assignmentInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	assignment, message := input.toAssignment()
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+	}
+	assignment.AssignmentID = assignmentID
+	
+	err = h.repo.Update(assignment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Assignment not found"})
+	}
+	if err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	updated, err := h.repo.GetByID(assignmentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h *AssignmentHandler) Delete(c echo.Context) error {
+	assignmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignment ID"})
+	}
+	
+	err = h.repo.Delete(assignmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Assignment not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMembers 特定日付時点で部門に所属する社員を取得
+func (h *AssignmentHandler) GetMembers(c echo.Context) error {
+	departmentID := c.Param("id")
+	
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	includeDescendants := false
+	if s := c.QueryParam("descendants"); s != "" {
+		includeDescendants, err = strconv.ParseBool(s)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "descendants must be true or false"})
+		}
+	}
+	
+	members, err := h.repo.GetMembers(departmentID, targetDate, includeDescendants)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if members == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"department_id": departmentID,
+		"date":          targetDate.Format("2006-01-02"),
+		"members":       members,
+	})
+}
+
+// GetHeadcount 特定日付時点の部門ごとの人数を取得
+func (h *AssignmentHandler) GetHeadcount(c echo.Context) error {
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	rootID := c.QueryParam("root")
+	
+	headcounts, err := h.repo.GetHeadcount(targetDate, rootID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if headcounts == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"date":        targetDate.Format("2006-01-02"),
+		"departments": headcounts,
+	})
+}
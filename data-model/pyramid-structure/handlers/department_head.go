@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DepartmentHeadHandler struct {
+	repo *models.DepartmentHeadRepository
+}
+
+func NewDepartmentHeadHandler(repo *models.DepartmentHeadRepository) *DepartmentHeadHandler {
+	return &DepartmentHeadHandler{repo: repo}
+}
+
+// departmentHeadInput 部門長の作成・更新のリクエスト
+type departmentHeadInput struct {
+	DepartmentID   string  `json:"department_id"`
+	EmployeeID     string  `json:"employee_id"`
+	EffectiveDate  string  `json:"effective_date"`
+	ExpirationDate *string `json:"expiration_date"`
+}
+
+// toDepartmentHead 入力を検証して部門長に変換する
+func (in departmentHeadInput) toDepartmentHead() (*models.DepartmentHead, string) {
+	if in.EmployeeID == "" || in.EffectiveDate == "" {
+		return nil, "Employee ID and effective date are required"
+	}
+	
+	effectiveDate, err := time.Parse("2006-01-02", in.EffectiveDate)
+	if err != nil {
+		return nil, "Invalid date format. Use YYYY-MM-DD"
+	}
+	
+	head := &models.DepartmentHead{
+		DepartmentID:  in.DepartmentID,
+		EmployeeID:    in.EmployeeID,
+		EffectiveDate: effectiveDate,
+	}
+	
+	if in.ExpirationDate != nil && *in.ExpirationDate != "" {
+		expirationDate, err := time.Parse("2006-01-02", *in.ExpirationDate)
+		if err != nil {
+			return nil, "Invalid date format. Use YYYY-MM-DD"
+		}
+		head.ExpirationDate = &expirationDate
+	}
+	
+	return head, ""
+}
+
+// GetByDepartment 部門の部門長の履歴を取得
+func (h *DepartmentHeadHandler) GetByDepartment(c echo.Context) error {
+	heads, err := h.repo.GetByDepartment(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, heads)
+}
+
+func (h *DepartmentHeadHandler) GetByID(c echo.Context) error {
+	headID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid department head ID"})
+	}
+	
+	head, err := h.repo.GetByID(headID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if head == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department head not found"})
+	}
+	
+	return c.JSON(http.StatusOK, head)
+}
+
+func (h *DepartmentHeadHandler) Create(c echo.Context) error {
+	var input departmentHeadInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if input.DepartmentID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Department ID is required"})
+	}
+	
+	head, message := input.toDepartmentHead()
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+	}
+	
+	if err := h.repo.Create(head); err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	// 作成後、部門名・社員名を含む完全なデータを取得
+	created, err := h.repo.GetByID(head.HeadID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (h *DepartmentHeadHandler) Update(c echo.Context) error {
+	headID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid department head ID"})
+	}
+	
+	var input departmentHeadInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	head, message := input.toDepartmentHead()
+	if message != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": message})
+	}
+	head.HeadID = headID
+	
+	err = h.repo.Update(head)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department head not found"})
+	}
+	if err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	updated, err := h.repo.GetByID(headID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (h *DepartmentHeadHandler) Delete(c echo.Context) error {
+	headID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid department head ID"})
+	}
+	
+	err = h.repo.Delete(headID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department head not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetApprovalChain 特定日付時点の承認ルート（起点の部門から上位部門の部門長の順）を取得
+func (h *DepartmentHeadHandler) GetApprovalChain(c echo.Context) error {
+	departmentID := c.Param("id")
+	
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	chain, err := h.repo.GetApprovalChain(departmentID, targetDate, c.QueryParam("requester"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if chain == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	return c.JSON(http.StatusOK, chain)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type DepartmentNameHandler struct {
+	repo *models.DepartmentNameRepository
+}
+
+func NewDepartmentNameHandler(repo *models.DepartmentNameRepository) *DepartmentNameHandler {
+	return &DepartmentNameHandler{repo: repo}
+}
+
+// GetHistory 部門名称の履歴を取得
+func (h *DepartmentNameHandler) GetHistory(c echo.Context) error {
+	names, err := h.repo.GetHistory(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, names)
+}
+
+func (h *DepartmentNameHandler) GetByID(c echo.Context) error {
+	effectiveDate, err := time.Parse("2006-01-02", c.Param("effective_date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	name, err := h.repo.GetByID(c.Param("department_id"), effectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if name == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department name not found"})
+	}
+	
+	return c.JSON(http.StatusOK, name)
+}
+
+func (h *DepartmentNameHandler) Create(c echo.Context) error {
+	var input struct {
+		DepartmentID   string  `json:"department_id"`
+		EffectiveDate  string  `json:"effective_date"`
+		DepartmentName string  `json:"department_name"`
+		ShortName      *string `json:"short_name"`
+	}
+	
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if input.DepartmentID == "" || input.EffectiveDate == "" || input.DepartmentName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Department ID, effective date and name are required"})
+	}
+	
+	effectiveDate, err := time.Parse("2006-01-02", input.EffectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	name := &models.DepartmentName{
+		DepartmentID:   input.DepartmentID,
+		EffectiveDate:  effectiveDate,
+		DepartmentName: input.DepartmentName,
+		ShortName:      input.ShortName,
+	}
+	
+	if err := h.repo.Create(name); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	// 作成後、失効年月を含む完全なデータを取得
+	createdName, err := h.repo.GetByID(name.DepartmentID, name.EffectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusCreated, createdName)
+}
+
+func (h *DepartmentNameHandler) Update(c echo.Context) error {
+	effectiveDate, err := time.Parse("2006-01-02", c.Param("effective_date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	var input struct {
+		DepartmentName string  `json:"department_name"`
+		ShortName      *string `json:"short_name"`
+	}
+	
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if input.DepartmentName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Department name is required"})
+	}
+	
+	name := &models.DepartmentName{
+		DepartmentID:   c.Param("department_id"),
+		EffectiveDate:  effectiveDate,
+		DepartmentName: input.DepartmentName,
+		ShortName:      input.ShortName,
+	}
+	
+	if err := h.repo.Update(name); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	// 更新後、失効年月を含む完全なデータを取得
+	updatedName, err := h.repo.GetByID(name.DepartmentID, name.EffectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if updatedName == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department name not found"})
+	}
+	
+	return c.JSON(http.StatusOK, updatedName)
+}
+
+func (h *DepartmentNameHandler) Delete(c echo.Context) error {
+	effectiveDate, err := time.Parse("2006-01-02", c.Param("effective_date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	if err := h.repo.Delete(c.Param("department_id"), effectiveDate); err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	return c.NoContent(http.StatusNoContent)
+}
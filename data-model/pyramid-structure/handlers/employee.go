@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type EmployeeHandler struct {
+	repo           *models.EmployeeRepository
+	assignmentRepo *models.AssignmentRepository
+}
+
+func NewEmployeeHandler(repo *models.EmployeeRepository, assignmentRepo *models.AssignmentRepository) *EmployeeHandler {
+	return &EmployeeHandler{repo: repo, assignmentRepo: assignmentRepo}
+}
+
+func (h *EmployeeHandler) GetAll(c echo.Context) error {
+	employees, err := h.repo.GetAll()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, employees)
+}
+
+func (h *EmployeeHandler) GetByID(c echo.Context) error {
+	employee, err := h.repo.GetByID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if employee == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Employee not found"})
+	}
+	
+	return c.JSON(http.StatusOK, employee)
+}
+
+func (h *EmployeeHandler) Create(c echo.Context) error {
+	var employee models.Employee
+	if err := c.Bind(&employee); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if employee.EmployeeID == "" || employee.EmployeeName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Employee ID and name are required"})
+	}
+	
+	if err := h.repo.Create(&employee); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusCreated, employee)
+}
+
+func (h *EmployeeHandler) Update(c echo.Context) error {
+	var employee models.Employee
+	if err := c.Bind(&employee); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	employee.EmployeeID = c.Param("id")
+	
+	if employee.EmployeeName == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Employee name is required"})
+	}
+	
+	if err := h.repo.Update(&employee); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if employee.CreatedAt.IsZero() {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Employee not found"})
+	}
+	
+	return c.JSON(http.StatusOK, employee)
+}
+
+func (h *EmployeeHandler) Delete(c echo.Context) error {
+	err := h.repo.Delete(c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Employee not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAssignments 社員の所属の履歴を取得
+func (h *EmployeeHandler) GetAssignments(c echo.Context) error {
+	assignments, err := h.assignmentRepo.GetByEmployee(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, assignments)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ImportHandler struct {
+	repo *models.ImportRepository
+}
+
+func NewImportHandler(repo *models.ImportRepository) *ImportHandler {
+	return &ImportHandler{repo: repo}
+}
+
+// ImportDepartments 部門と期間別の部門名称を CSV から取り込み
+func (h *ImportHandler) ImportDepartments(c echo.Context) error {
+	return h.importCSV(c, h.repo.ImportDepartmentsCSV)
+}
+
+// ImportAttributes 期間別の組織属性を CSV から取り込み
+func (h *ImportHandler) ImportAttributes(c echo.Context) error {
+	return h.importCSV(c, h.repo.ImportAttributesCSV)
+}
+
+func (h *ImportHandler) importCSV(c echo.Context, importFn func(r io.Reader, dryRun bool) (*models.ImportResult, error)) error {
+	dryRun, err := queryDryRun(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	body, err := csvBody(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	defer body.Close()
+	
+	result, err := importFn(body, dryRun)
+	if err != nil {
+		return importError(c, err)
+	}
+	
+	return c.JSON(http.StatusOK, result)
+}
+
+// ExportSnapshot 期間別の組織構造全体を JSON のスナップショットとして出力
+func (h *ImportHandler) ExportSnapshot(c echo.Context) error {
+	snapshot, err := h.repo.ExportSnapshot()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	filename := fmt.Sprintf("org-snapshot-%s.json", snapshot.ExportedAt.Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.JSON(http.StatusOK, snapshot)
+}
+
+// ImportSnapshot スナップショットで期間別の組織構造全体を置き換え
+func (h *ImportHandler) ImportSnapshot(c echo.Context) error {
+	dryRun, err := queryDryRun(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	var snapshot models.Snapshot
+	if err := c.Bind(&snapshot); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	result, err := h.repo.ImportSnapshot(&snapshot, dryRun)
+	if err != nil {
+		return importError(c, err)
+	}
+	
+	return c.JSON(http.StatusOK, result)
+}
+
+// queryDryRun dry_run パラメータを読む（省略時は false）
+func queryDryRun(c echo.Context) (bool, error) {
+	s := c.QueryParam("dry_run")
+	if s == "" {
+		return false, nil
+	}
+	
+	dryRun, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.New("Invalid dry_run. Use true or false")
+	}
+	return dryRun, nil
+}
+
+// csvBody CSV の本文を返す
+// multipart/form-data の場合は file フィールドのファイル、それ以外はリクエスト本文をそのまま CSV として読む
+func csvBody(c echo.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return c.Request().Body, nil
+	}
+	
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, errors.New("CSV file is required in the file field")
+	}
+	return fileHeader.Open()
+}
+
+// importError 取り込みで発生したエラーをレスポンスに変換する
+func importError(c echo.Context, err error) error {
+	var validationErr *models.ImportValidationError
+	if errors.As(err, &validationErr) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  err.Error(),
+			"issues": validationErr.Issues,
+		})
+	}
+	
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"org-hierarchy/models"
@@ -49,7 +51,17 @@ func (h *OrganizationAttributeHandler) GetByID(c echo.Context) error {
 func (h *OrganizationAttributeHandler) GetDepartmentHistory(c echo.Context) error {
 	departmentID := c.Param("id")
 	
-	attrs, err := h.repo.GetDepartmentHistory(departmentID)
+	knownAt, err := queryKnownAt(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	var attrs []models.OrganizationAttribute
+	if knownAt != nil {
+		attrs, err = h.repo.GetDepartmentHistoryAsKnownAt(departmentID, *knownAt)
+	} else {
+		attrs, err = h.repo.GetDepartmentHistory(departmentID)
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -62,6 +74,7 @@ func (h *OrganizationAttributeHandler) Create(c echo.Context) error {
 		DepartmentID       string  `json:"department_id"`
 		EffectiveDate      string  `json:"effective_date"`
 		ParentDepartmentID *string `json:"parent_department_id"`
+		Abolished          bool    `json:"abolished"`
 	}
 	
 	if err := c.Bind(&input); err != nil {
@@ -81,10 +94,11 @@ func (h *OrganizationAttributeHandler) Create(c echo.Context) error {
 		DepartmentID:       input.DepartmentID,
 		EffectiveDate:      effectiveDate,
 		ParentDepartmentID: input.ParentDepartmentID,
+		Abolished:          input.Abolished,
 	}
 	
 	if err := h.repo.Create(attr); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
 	}
 	
 	// 作成後、失効年月を含む完全なデータを取得
@@ -107,20 +121,36 @@ func (h *OrganizationAttributeHandler) Update(c echo.Context) error {
 	
 	var input struct {
 		ParentDepartmentID *string `json:"parent_department_id"`
+		Abolished          *bool   `json:"abolished"`
 	}
 	
 	if err := c.Bind(&input); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
 	
+	current, err := h.repo.GetByID(departmentID, effectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if current == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Organization attribute not found"})
+	}
+	
 	attr := &models.OrganizationAttribute{
 		DepartmentID:       departmentID,
 		EffectiveDate:      effectiveDate,
 		ParentDepartmentID: input.ParentDepartmentID,
+		Abolished:          current.Abolished,
+	}
+	
+	// abolished が省略された場合は現在の値を維持する
+	if input.Abolished != nil {
+		attr.Abolished = *input.Abolished
 	}
 	
 	if err := h.repo.Update(attr); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
 	}
 	
 	// 更新後、失効年月を含む完全なデータを取得
@@ -142,12 +172,49 @@ func (h *OrganizationAttributeHandler) Delete(c echo.Context) error {
 	}
 	
 	if err := h.repo.Delete(departmentID, effectiveDate); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
 	}
 	
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Abolish 部門の廃止レコードを登録
+func (h *OrganizationAttributeHandler) Abolish(c echo.Context) error {
+	var input struct {
+		EffectiveDate string `json:"effective_date"`
+	}
+	
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	
+	if input.EffectiveDate == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Effective date is required"})
+	}
+	
+	effectiveDate, err := time.Parse("2006-01-02", input.EffectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	attr := &models.OrganizationAttribute{
+		DepartmentID:  c.Param("id"),
+		EffectiveDate: effectiveDate,
+		Abolished:     true,
+	}
+	
+	if err := h.repo.Create(attr); err != nil {
+		return c.JSON(writeErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	
+	createdAttr, err := h.repo.GetByID(attr.DepartmentID, attr.EffectiveDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusCreated, createdAttr)
+}
+
 // GetHierarchyByDate 特定日付時点の組織階層を取得
 func (h *OrganizationAttributeHandler) GetHierarchyByDate(c echo.Context) error {
 	dateStr := c.QueryParam("date")
@@ -161,13 +228,293 @@ func (h *OrganizationAttributeHandler) GetHierarchyByDate(c echo.Context) error
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
 	}
 	
-	attrs, err := h.repo.GetHierarchyByDate(targetDate)
+	knownAt, err := queryKnownAt(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	var attrs []models.OrganizationAttribute
+	if knownAt != nil {
+		attrs, err = h.repo.GetHierarchyAsKnownAt(targetDate, *knownAt)
+	} else {
+		attrs, err = h.repo.GetHierarchyByDate(targetDate)
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"date": targetDate.Format("2006-01-02"),
 		"attributes": attrs,
+	}
+	if knownAt != nil {
+		response["as_known_at"] = knownAt.Format(knownAtLayout)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetHierarchyTree 特定日付時点の組織階層をツリー形式で取得
+func (h *OrganizationAttributeHandler) GetHierarchyTree(c echo.Context) error {
+	dateStr := c.QueryParam("date")
+	if dateStr == "" {
+		dateStr = time.Now().Format("2006-01-02")
+	}
+	
+	targetDate, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	maxDepth := -1
+	if maxDepthStr := c.QueryParam("max_depth"); maxDepthStr != "" {
+		maxDepth, err = strconv.Atoi(maxDepthStr)
+		if err != nil || maxDepth < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "max_depth must be a non-negative integer"})
+		}
+	}
+	
+	knownAt, err := queryKnownAt(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	rootID := c.QueryParam("root")
+	tree, err := h.repo.GetHierarchyTree(targetDate, knownAt, rootID, maxDepth)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if tree == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Root department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	response := map[string]interface{}{
+		"date": targetDate.Format("2006-01-02"),
+		"tree": tree,
+	}
+	if knownAt != nil {
+		response["as_known_at"] = knownAt.Format(knownAtLayout)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetHierarchyDiff 2時点間の組織階層の差分を取得
+func (h *OrganizationAttributeHandler) GetHierarchyDiff(c echo.Context) error {
+	fromStr := c.QueryParam("from")
+	toStr := c.QueryParam("to")
+	if fromStr == "" || toStr == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from and to are required"})
+	}
+	
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	knownAt, err := queryKnownAt(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	diff, err := h.repo.GetHierarchyDiff(from, to, knownAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if c.QueryParam("format") == "text" {
+		return c.String(http.StatusOK, diff.Announcement())
+	}
+	
+	return c.JSON(http.StatusOK, diff)
+}
+
+// GetTimeline 組織が変化した発効日と、その日の新設・移管・廃止・改称の一覧を取得
+func (h *OrganizationAttributeHandler) GetTimeline(c echo.Context) error {
+	from, err := queryOptionalDate(c, "from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	to, err := queryOptionalDate(c, "to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	filter := models.TimelineFilter{From: from, To: to, DepartmentID: c.QueryParam("department")}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "to must not be before from"})
+	}
+	
+	timeline, err := h.repo.GetTimeline(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, timeline)
+}
+
+// ExportHierarchy 特定日付時点の組織図を DOT・Mermaid・SVG 形式で出力
+func (h *OrganizationAttributeHandler) ExportHierarchy(c echo.Context) error {
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	format := c.QueryParam("format")
+	if format == "" {
+		format = models.ChartFormatSVG
+	}
+	if format != models.ChartFormatDOT && format != models.ChartFormatMermaid && format != models.ChartFormatSVG {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be dot, mermaid or svg"})
+	}
+	
+	knownAt, err := queryKnownAt(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	
+	tree, err := h.repo.GetHierarchyTree(targetDate, knownAt, c.QueryParam("root"), -1)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if tree == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Root department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	switch format {
+	case models.ChartFormatDOT:
+		return c.Blob(http.StatusOK, "text/vnd.graphviz; charset=UTF-8", []byte(models.RenderDOT(tree, targetDate)))
+	case models.ChartFormatMermaid:
+		return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, []byte(models.RenderMermaid(tree, targetDate)))
+	}
+	return c.Blob(http.StatusOK, "image/svg+xml; charset=UTF-8", []byte(models.RenderSVG(tree, targetDate)))
+}
+
+// GetAncestors 特定日付時点の上位部門を取得
+func (h *OrganizationAttributeHandler) GetAncestors(c echo.Context) error {
+	departmentID := c.Param("id")
+	
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	ancestors, err := h.repo.GetAncestors(departmentID, targetDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if ancestors == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"department_id": departmentID,
+		"date":          targetDate.Format("2006-01-02"),
+		"ancestors":     ancestors,
 	})
-}
\ No newline at end of file
+}
+
+// GetDescendants 特定日付時点の配下の部門を取得
+func (h *OrganizationAttributeHandler) GetDescendants(c echo.Context) error {
+	departmentID := c.Param("id")
+	
+	targetDate, err := queryDate(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+	
+	depth := 0
+	if depthStr := c.QueryParam("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "depth must be a positive integer"})
+		}
+	}
+	
+	descendants, err := h.repo.GetDescendants(departmentID, targetDate, depth)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if descendants == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Department not found in hierarchy on " + targetDate.Format("2006-01-02")})
+	}
+	
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"department_id": departmentID,
+		"date":          targetDate.Format("2006-01-02"),
+		"descendants":   descendants,
+	})
+}
+
+// GetDepartmentVersions 部門の組織属性の記録時点の履歴を取得
+func (h *OrganizationAttributeHandler) GetDepartmentVersions(c echo.Context) error {
+	versions, err := h.repo.GetDepartmentVersions(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, versions)
+}
+
+const knownAtLayout = "2006-01-02T15:04:05.999999"
+
+// queryKnownAt as_known_at クエリパラメータを解析する（省略時は nil）
+// 日時はデータベースの記録日時と同じタイムゾーンで解釈し、日付のみの場合はその日の終わり時点とする
+func queryKnownAt(c echo.Context) (*time.Time, error) {
+	s := c.QueryParam("as_known_at")
+	if s == "" {
+		return nil, nil
+	}
+	
+	if date, err := time.Parse("2006-01-02", s); err == nil {
+		knownAt := date.AddDate(0, 0, 1).Add(-time.Microsecond)
+		return &knownAt, nil
+	}
+	
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if knownAt, err := time.Parse(layout, s); err == nil {
+			return &knownAt, nil
+		}
+	}
+	
+	return nil, errors.New("Invalid as_known_at format. Use YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS")
+}
+
+// queryDate date クエリパラメータを解析する（省略時は現在日付）
+func queryDate(c echo.Context) (time.Time, error) {
+	dateStr := c.QueryParam("date")
+	if dateStr == "" {
+		dateStr = time.Now().Format("2006-01-02")
+	}
+	return time.Parse("2006-01-02", dateStr)
+}
+
+// queryOptionalDate 省略可能な日付パラメータを読む（省略時は nil）
+func queryOptionalDate(c echo.Context, name string) (*time.Time, error) {
+	s := c.QueryParam(name)
+	if s == "" {
+		return nil, nil
+	}
+	
+	date, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// writeErrorStatus 組織属性の書き込みエラーに対応するHTTPステータスを返す
+func writeErrorStatus(err error) int {
+	if models.IsValidationError(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"org-hierarchy/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReorganizationPlanHandler struct {
+	repo *models.ReorganizationPlanRepository
+}
+
+func NewReorganizationPlanHandler(repo *models.ReorganizationPlanRepository) *ReorganizationPlanHandler {
+	return &ReorganizationPlanHandler{repo: repo}
+}
+
+type reorganizationPlanInput struct {
+	Name          string                        `json:"name"`
+	EffectiveDate string                        `json:"effective_date"`
+	Changes       []models.ReorganizationChange `json:"changes"`
+}
+
+func (h *ReorganizationPlanHandler) GetAll(c echo.Context) error {
+	plans, err := h.repo.GetAll()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, plans)
+}
+
+func (h *ReorganizationPlanHandler) GetByID(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid plan ID"})
+	}
+	
+	plan, err := h.repo.GetByID(planID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	if plan == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Reorganization plan not found"})
+	}
+	
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (h *ReorganizationPlanHandler) Create(c echo.Context) error {
+	plan, errResp := bindPlan(c)
+	if errResp != nil {
+		return c.JSON(http.StatusBadRequest, errResp)
+	}
+	
+	if err := h.repo.Create(plan); err != nil {
+		return planError(c, err)
+	}
+	
+	return c.JSON(http.StatusCreated, plan)
+}
+
+func (h *ReorganizationPlanHandler) Update(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid plan ID"})
+	}
+	
+	plan, errResp := bindPlan(c)
+	if errResp != nil {
+		return c.JSON(http.StatusBadRequest, errResp)
+	}
+	plan.PlanID = planID
+	
+	if err := h.repo.Update(plan); err != nil {
+		return planError(c, err)
+	}
+	
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Preview 計画を適用した場合の組織階層と検証結果を取得
+func (h *ReorganizationPlanHandler) Preview(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid plan ID"})
+	}
+	
+	preview, err := h.repo.Preview(planID)
+	if err != nil {
+		return planError(c, err)
+	}
+	
+	if preview == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Reorganization plan not found"})
+	}
+	
+	return c.JSON(http.StatusOK, preview)
+}
+
+// Apply 計画を1トランザクションで適用
+func (h *ReorganizationPlanHandler) Apply(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid plan ID"})
+	}
+	
+	plan, err := h.repo.Apply(planID)
+	if err != nil {
+		return planError(c, err)
+	}
+	
+	return c.JSON(http.StatusOK, plan)
+}
+
+// Cancel 計画を取り消し
+func (h *ReorganizationPlanHandler) Cancel(c echo.Context) error {
+	planID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid plan ID"})
+	}
+	
+	if err := h.repo.Cancel(planID); err != nil {
+		return planError(c, err)
+	}
+	
+	plan, err := h.repo.GetByID(planID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	
+	return c.JSON(http.StatusOK, plan)
+}
+
+func bindPlan(c echo.Context) (*models.ReorganizationPlan, map[string]string) {
+	var input reorganizationPlanInput
+	if err := c.Bind(&input); err != nil {
+		return nil, map[string]string{"error": "Invalid request body"}
+	}
+	
+	if input.EffectiveDate == "" {
+		return nil, map[string]string{"error": "Effective date is required"}
+	}
+	
+	effectiveDate, err := time.Parse("2006-01-02", input.EffectiveDate)
+	if err != nil {
+		return nil, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"}
+	}
+	
+	return &models.ReorganizationPlan{
+		Name:          input.Name,
+		EffectiveDate: effectiveDate,
+		Changes:       input.Changes,
+	}, nil
+}
+
+// planError 組織改編計画の操作で発生したエラーをレスポンスに変換する
+func planError(c echo.Context, err error) error {
+	var validationErr *models.PlanValidationError
+	var stateErr *models.PlanStateError
+	
+	switch {
+	case errors.As(err, &validationErr):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  err.Error(),
+			"issues": validationErr.Issues,
+		})
+	case errors.As(err, &stateErr):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Reorganization plan not found"})
+	}
+	
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -1,26 +1,141 @@
 -- 部門テーブル
 CREATE TABLE IF NOT EXISTS departments (
     department_id VARCHAR(50) PRIMARY KEY,
-    department_name VARCHAR(255) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 期間別部門名称テーブル
+CREATE TABLE IF NOT EXISTS department_names (
+    department_id VARCHAR(50) NOT NULL,
+    effective_date DATE NOT NULL,
+    department_name VARCHAR(255) NOT NULL,
+    short_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (department_id, effective_date),
+    FOREIGN KEY (department_id) REFERENCES departments(department_id) ON DELETE CASCADE
+);
+
 -- 期間別組織属性テーブル
 CREATE TABLE IF NOT EXISTS organization_attributes (
     department_id VARCHAR(50) NOT NULL,
     effective_date DATE NOT NULL,
     parent_department_id VARCHAR(50),
+    is_abolished BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (department_id, effective_date),
     FOREIGN KEY (department_id) REFERENCES departments(department_id) ON DELETE CASCADE,
-    FOREIGN KEY (parent_department_id) REFERENCES departments(department_id)
+    FOREIGN KEY (parent_department_id) REFERENCES departments(department_id),
+    -- 廃止レコードは上位部門を持たない
+    CHECK (NOT is_abolished OR parent_department_id IS NULL)
+);
+
+-- 組織属性の記録時点の履歴テーブル
+-- 組織属性の作成・更新・削除のたびにトリガーで記録する（recorded_at から superseded_at の直前までシステムに登録されていた内容）
+CREATE TABLE IF NOT EXISTS organization_attribute_versions (
+    version_id BIGSERIAL PRIMARY KEY,
+    department_id VARCHAR(50) NOT NULL,
+    effective_date DATE NOT NULL,
+    parent_department_id VARCHAR(50),
+    is_abolished BOOLEAN NOT NULL,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    recorded_at TIMESTAMP NOT NULL,
+    superseded_at TIMESTAMP,
+    CHECK (superseded_at IS NULL OR superseded_at >= recorded_at)
+);
+
+-- 組織階層閉包テーブル（組織属性から導出）
+-- 部門ごとに、自部門（depth = 0）と各上位部門（depth = 1 が直属の上位部門）を有効期間付きで保持する
+-- 自部門の行は廃止レコード以外の組織属性レコードと1対1に対応し、valid_to はその失効年月日
+CREATE TABLE IF NOT EXISTS organization_closure (
+    department_id VARCHAR(50) NOT NULL,
+    ancestor_id VARCHAR(50) NOT NULL,
+    depth INTEGER NOT NULL CHECK (depth >= 0),
+    valid_from DATE NOT NULL,
+    valid_to DATE,
+    PRIMARY KEY (department_id, ancestor_id, valid_from),
+    FOREIGN KEY (department_id) REFERENCES departments(department_id) ON DELETE CASCADE,
+    FOREIGN KEY (ancestor_id) REFERENCES departments(department_id) ON DELETE CASCADE,
+    CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+-- 社員テーブル
+CREATE TABLE IF NOT EXISTS employees (
+    employee_id VARCHAR(50) PRIMARY KEY,
+    employee_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 期間別所属テーブル
+-- 同じ社員が複数の部門に同時に所属できる（主務は同時に1件まで、残りは兼務）
+CREATE TABLE IF NOT EXISTS employee_assignments (
+    assignment_id BIGSERIAL PRIMARY KEY,
+    employee_id VARCHAR(50) NOT NULL,
+    department_id VARCHAR(50) NOT NULL,
+    effective_date DATE NOT NULL,
+    expiration_date DATE,
+    position VARCHAR(100),
+    is_primary BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id) ON DELETE CASCADE,
+    FOREIGN KEY (department_id) REFERENCES departments(department_id),
+    CHECK (expiration_date IS NULL OR expiration_date >= effective_date)
+);
+
+-- 期間別部門長テーブル
+CREATE TABLE IF NOT EXISTS department_heads (
+    head_id BIGSERIAL PRIMARY KEY,
+    department_id VARCHAR(50) NOT NULL,
+    employee_id VARCHAR(50) NOT NULL,
+    effective_date DATE NOT NULL,
+    expiration_date DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (department_id) REFERENCES departments(department_id) ON DELETE CASCADE,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id) ON DELETE CASCADE,
+    CHECK (expiration_date IS NULL OR expiration_date >= effective_date)
+);
+
+-- 組織改編計画テーブル
+CREATE TABLE IF NOT EXISTS reorganization_plans (
+    plan_id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    effective_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'applied', 'cancelled')),
+    applied_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 組織改編計画の変更内容テーブル
+CREATE TABLE IF NOT EXISTS reorganization_plan_changes (
+    plan_id BIGINT NOT NULL,
+    seq INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'move', 'abolish')),
+    department_id VARCHAR(50) NOT NULL,
+    parent_department_id VARCHAR(50),
+    department_name VARCHAR(255),
+    short_name VARCHAR(50),
+    PRIMARY KEY (plan_id, seq),
+    FOREIGN KEY (plan_id) REFERENCES reorganization_plans(plan_id) ON DELETE CASCADE
 );
 
 -- インデックスの作成
 CREATE INDEX idx_org_attr_parent ON organization_attributes(parent_department_id);
 CREATE INDEX idx_org_attr_effective_date ON organization_attributes(effective_date);
+CREATE INDEX idx_org_attr_versions_department ON organization_attribute_versions(department_id, effective_date, recorded_at);
+CREATE INDEX idx_org_attr_versions_recorded ON organization_attribute_versions(recorded_at, superseded_at);
+CREATE INDEX idx_org_closure_self ON organization_closure(valid_from, valid_to) WHERE depth = 0;
+CREATE INDEX idx_org_closure_ancestor ON organization_closure(ancestor_id, valid_from);
+CREATE INDEX idx_assignments_employee ON employee_assignments(employee_id);
+CREATE INDEX idx_assignments_department ON employee_assignments(department_id, effective_date);
+CREATE INDEX idx_department_heads_department ON department_heads(department_id, effective_date);
+CREATE INDEX idx_department_heads_employee ON department_heads(employee_id);
 
 -- 更新日時を自動更新するトリガー
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -31,33 +146,174 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+-- 組織属性の変更を記録時点の履歴に記録する
+-- 記録日時はトランザクションで最初に組織属性を変更した日時（テーブルのロック取得後）とし、同じトランザクションでの複数の変更は同時に記録する
+-- トランザクションの開始日時（now()）では、ロック待ちの間に後から開始したトランザクションが先にコミットし、記録日時の順序がコミット順と食い違う
+CREATE OR REPLACE FUNCTION record_organization_attribute_version()
+RETURNS TRIGGER AS $$
+DECLARE
+    recorded TIMESTAMP;
+BEGIN
+    recorded := NULLIF(current_setting('org_hierarchy.recorded_at', true), '')::timestamp;
+    IF recorded IS NULL THEN
+        recorded := clock_timestamp();
+        PERFORM set_config('org_hierarchy.recorded_at', recorded::text, true);
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE organization_attribute_versions
+        SET superseded_at = recorded
+        WHERE department_id = OLD.department_id
+        AND effective_date = OLD.effective_date
+        AND superseded_at IS NULL;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO organization_attribute_versions
+            (department_id, effective_date, parent_department_id, is_abolished, created_at, updated_at, recorded_at)
+        VALUES
+            (NEW.department_id, NEW.effective_date, NEW.parent_department_id, NEW.is_abolished, NEW.created_at, NEW.updated_at, recorded);
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ language 'plpgsql';
+
+-- 組織階層閉包テーブルを再構築する
+-- changed に含まれる部門と、いずれかの時点でその配下にあった部門の行を作り直す（NULL の場合はすべての部門）
+CREATE OR REPLACE FUNCTION refresh_organization_closure(changed TEXT[])
+RETURNS VOID AS $$
+DECLARE
+    affected TEXT[];
+BEGIN
+    IF changed IS NULL THEN
+        SELECT array_agg(department_id) INTO affected FROM departments;
+    ELSE
+        WITH RECURSIVE below(department_id) AS (
+            SELECT unnest(changed)
+            UNION
+            SELECT oa.department_id::text
+            FROM organization_attributes oa
+            JOIN below b ON oa.parent_department_id = b.department_id
+        )
+        SELECT array_agg(department_id) INTO affected FROM below;
+    END IF;
+
+    DELETE FROM organization_closure WHERE department_id = ANY(affected);
+
+    INSERT INTO organization_closure (department_id, ancestor_id, depth, valid_from, valid_to)
+    WITH RECURSIVE periods AS (
+        -- valid_until は次のレコードの発効日（最新のレコードは NULL）
+        SELECT
+            department_id,
+            parent_department_id,
+            is_abolished,
+            effective_date AS valid_from,
+            LEAD(effective_date) OVER (PARTITION BY department_id ORDER BY effective_date) AS valid_until
+        FROM organization_attributes
+    ),
+    active AS (
+        SELECT * FROM periods WHERE NOT is_abolished
+    ),
+    chain AS (
+        SELECT
+            a.department_id::text AS department_id,
+            a.department_id::text AS ancestor_id,
+            0 AS depth,
+            a.parent_department_id::text AS next_id,
+            a.valid_from,
+            a.valid_until,
+            ARRAY[a.department_id::text] AS path
+        FROM active a
+        WHERE a.department_id = ANY(affected)
+        UNION ALL
+        -- 上位部門のレコードと期間が重なる部分ごとに1階層上へたどる
+        SELECT
+            c.department_id,
+            p.department_id::text,
+            c.depth + 1,
+            p.parent_department_id::text,
+            GREATEST(c.valid_from, p.valid_from),
+            LEAST(c.valid_until, p.valid_until),
+            c.path || p.department_id::text
+        FROM chain c
+        JOIN active p ON p.department_id = c.next_id
+        WHERE p.valid_from < COALESCE(c.valid_until, 'infinity')
+        AND COALESCE(p.valid_until, 'infinity') > c.valid_from
+        AND NOT p.department_id = ANY(c.path)
+    )
+    SELECT department_id, ancestor_id, depth, valid_from, valid_until - 1
+    FROM chain;
+END;
+$$ language 'plpgsql';
+
 CREATE TRIGGER update_departments_updated_at BEFORE UPDATE
     ON departments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 CREATE TRIGGER update_organization_attributes_updated_at BEFORE UPDATE
     ON organization_attributes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER record_organization_attribute_versions AFTER INSERT OR UPDATE OR DELETE
+    ON organization_attributes FOR EACH ROW EXECUTE FUNCTION record_organization_attribute_version();
+
+CREATE TRIGGER update_department_names_updated_at BEFORE UPDATE
+    ON department_names FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_reorganization_plans_updated_at BEFORE UPDATE
+    ON reorganization_plans FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_employees_updated_at BEFORE UPDATE
+    ON employees FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_employee_assignments_updated_at BEFORE UPDATE
+    ON employee_assignments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_department_heads_updated_at BEFORE UPDATE
+    ON department_heads FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 -- サンプルデータの投入
 -- 部門マスタ
-INSERT INTO departments (department_id, department_name) VALUES
-('HQ', '本社'),
-('STRATEGY', '経営企画部'),
-('GENERAL', '総務部'),
-('HR', '人事部'),
-('FINANCE', '財務部'),
-('SALES_HQ', '営業本部'),
-('SALES_1', '営業1部'),
-('SALES_2', '営業2部'),
-('SALES_SUPPORT', '営業支援部'),
-('TECH_HQ', '技術本部'),
-('DEV', '開発部'),
-('RESEARCH', '研究部'),
-('QA', '品質管理部'),
-('MFG_HQ', '製造本部'),
-('MFG_1', '製造1部'),
-('MFG_2', '製造2部'),
-('IT', 'IT推進部'),
-('DIGITAL', 'デジタル戦略部');
+INSERT INTO departments (department_id) VALUES
+('HQ'),
+('STRATEGY'),
+('GENERAL'),
+('HR'),
+('FINANCE'),
+('SALES_HQ'),
+('SALES_1'),
+('SALES_2'),
+('SALES_SUPPORT'),
+('TECH_HQ'),
+('DEV'),
+('RESEARCH'),
+('QA'),
+('MFG_HQ'),
+('MFG_1'),
+('MFG_2'),
+('IT'),
+('DIGITAL');
+
+-- 部門名称
+INSERT INTO department_names (department_id, effective_date, department_name) VALUES
+('HQ', '2023-01-01', '本社'),
+('STRATEGY', '2023-01-01', '経営企画部'),
+('GENERAL', '2023-01-01', '総務部'),
+('HR', '2023-01-01', '人事部'),
+('FINANCE', '2023-01-01', '財務部'),
+('SALES_HQ', '2023-01-01', '営業本部'),
+('SALES_1', '2023-01-01', '営業1部'),
+('SALES_2', '2023-01-01', '営業2部'),
+('SALES_SUPPORT', '2023-01-01', '営業支援部'),
+('TECH_HQ', '2023-01-01', '技術本部'),
+('DEV', '2023-01-01', '開発部'),
+('RESEARCH', '2023-01-01', '研究部'),
+('QA', '2023-01-01', '品質管理部'),
+('MFG_HQ', '2023-01-01', '製造本部'),
+('MFG_1', '2023-01-01', '製造1部'),
+('MFG_2', '2023-01-01', '製造2部'),
+('IT', '2023-07-01', 'IT推進部'),
+('DIGITAL', '2024-04-01', 'デジタル戦略部');
 
 -- 2023年1月1日時点の組織構造
 INSERT INTO organization_attributes (department_id, effective_date, parent_department_id) VALUES
@@ -95,4 +351,94 @@ INSERT INTO organization_attributes (department_id, effective_date, parent_depar
 INSERT INTO organization_attributes (department_id, effective_date, parent_department_id) VALUES
 ('MFG_HQ', '2024-10-01', 'TECH_HQ'),
 ('MFG_1', '2024-10-01', 'MFG_HQ'),
-('MFG_2', '2024-10-01', 'MFG_HQ');
\ No newline at end of file
+('MFG_2', '2024-10-01', 'MFG_HQ');
+
+-- 組織属性の記録日時（サンプルデータは各レコードを発効日の1か月前に登録したものとする）
+UPDATE organization_attribute_versions SET recorded_at = effective_date - INTERVAL '1 month';
+
+-- デジタル戦略部は当初総務部配下として登録され、2024年3月15日に経営企画部配下に訂正されたものとする
+INSERT INTO organization_attribute_versions
+    (department_id, effective_date, parent_department_id, is_abolished, created_at, updated_at, recorded_at, superseded_at)
+VALUES
+    ('DIGITAL', '2024-04-01', 'GENERAL', FALSE, '2024-03-01', '2024-03-01', '2024-03-01', '2024-03-15');
+UPDATE organization_attribute_versions SET recorded_at = '2024-03-15'
+WHERE department_id = 'DIGITAL' AND superseded_at IS NULL;
+
+-- 社員
+INSERT INTO employees (employee_id, employee_name) VALUES
+('E001', '山田太郎'),
+('E002', '佐藤花子'),
+('E003', '鈴木一郎'),
+('E004', '高橋美咲'),
+('E005', '田中健'),
+('E006', '伊藤誠'),
+('E007', '渡辺直子'),
+('E008', '山本大輔'),
+('E009', '中村優'),
+('E010', '小林翔'),
+('E011', '加藤真理'),
+('E012', '吉田拓也'),
+('E013', '山口恵'),
+('E014', '松本剛'),
+('E015', '井上彩'),
+('E016', '木村健太'),
+('E017', '林結衣'),
+('E018', '清水悠'),
+('E019', '森本彩香');
+
+-- 所属（2023年1月1日時点）
+INSERT INTO employee_assignments (employee_id, department_id, effective_date, expiration_date, position, is_primary) VALUES
+('E001', 'HQ', '2023-01-01', NULL, '社長', TRUE),
+('E002', 'STRATEGY', '2023-01-01', NULL, '部長', TRUE),
+('E003', 'GENERAL', '2023-01-01', NULL, '部長', TRUE),
+('E004', 'HR', '2023-01-01', NULL, '部長', TRUE),
+('E005', 'FINANCE', '2023-01-01', NULL, '部長', TRUE),
+('E006', 'SALES_HQ', '2023-01-01', NULL, '本部長', TRUE),
+('E007', 'SALES_1', '2023-01-01', NULL, '部長', TRUE),
+('E008', 'SALES_2', '2023-01-01', NULL, '部長', TRUE),
+('E009', 'SALES_SUPPORT', '2023-01-01', NULL, '部長', TRUE),
+('E010', 'TECH_HQ', '2023-01-01', NULL, '本部長', TRUE),
+('E011', 'DEV', '2023-01-01', NULL, '部長', TRUE),
+('E012', 'DEV', '2023-01-01', NULL, '主任', TRUE),
+('E013', 'RESEARCH', '2023-01-01', NULL, '部長', TRUE),
+('E014', 'QA', '2023-01-01', NULL, '部長', TRUE),
+('E015', 'MFG_HQ', '2023-01-01', '2024-09-30', '本部長', TRUE),
+('E016', 'MFG_1', '2023-01-01', NULL, '部長', TRUE),
+('E018', 'GENERAL', '2023-01-01', '2023-06-30', '主任', TRUE);
+
+-- IT推進部の新設に伴う異動（2023年7月1日）
+INSERT INTO employee_assignments (employee_id, department_id, effective_date, expiration_date, position, is_primary) VALUES
+('E017', 'IT', '2023-07-01', NULL, '部長', TRUE),
+('E018', 'IT', '2023-07-01', NULL, '主任', TRUE);
+
+-- デジタル戦略部の新設（2024年4月1日、経営企画部長が部長を兼務）
+INSERT INTO employee_assignments (employee_id, department_id, effective_date, expiration_date, position, is_primary) VALUES
+('E002', 'DIGITAL', '2024-04-01', NULL, '部長', FALSE),
+('E019', 'DIGITAL', '2024-04-01', NULL, '課長', TRUE);
+
+-- 製造本部の統合に伴う異動（2024年10月1日）
+INSERT INTO employee_assignments (employee_id, department_id, effective_date, expiration_date, position, is_primary) VALUES
+('E015', 'TECH_HQ', '2024-10-01', NULL, '副本部長', TRUE);
+
+-- 部門長（製造2部は部門長が空席。製造本部は統合に伴い2024年10月1日から空席）
+INSERT INTO department_heads (department_id, employee_id, effective_date, expiration_date) VALUES
+('HQ', 'E001', '2023-01-01', NULL),
+('STRATEGY', 'E002', '2023-01-01', NULL),
+('GENERAL', 'E003', '2023-01-01', NULL),
+('HR', 'E004', '2023-01-01', NULL),
+('FINANCE', 'E005', '2023-01-01', NULL),
+('SALES_HQ', 'E006', '2023-01-01', NULL),
+('SALES_1', 'E007', '2023-01-01', NULL),
+('SALES_2', 'E008', '2023-01-01', NULL),
+('SALES_SUPPORT', 'E009', '2023-01-01', NULL),
+('TECH_HQ', 'E010', '2023-01-01', NULL),
+('DEV', 'E011', '2023-01-01', NULL),
+('RESEARCH', 'E013', '2023-01-01', NULL),
+('QA', 'E014', '2023-01-01', NULL),
+('MFG_HQ', 'E015', '2023-01-01', '2024-09-30'),
+('MFG_1', 'E016', '2023-01-01', NULL),
+('IT', 'E017', '2023-07-01', NULL),
+('DIGITAL', 'E002', '2024-04-01', NULL);
+
+-- 組織階層閉包テーブルの構築
+SELECT refresh_organization_closure(NULL);
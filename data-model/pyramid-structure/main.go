@@ -38,9 +38,21 @@ func main() {
 	// ハンドラーの初期化
 	departmentRepo := models.NewDepartmentRepository(db)
 	orgAttrRepo := models.NewOrganizationAttributeRepository(db)
+	departmentNameRepo := models.NewDepartmentNameRepository(db)
+	planRepo := models.NewReorganizationPlanRepository(db)
+	employeeRepo := models.NewEmployeeRepository(db)
+	assignmentRepo := models.NewAssignmentRepository(db)
+	importRepo := models.NewImportRepository(db)
+	departmentHeadRepo := models.NewDepartmentHeadRepository(db)
 	
 	departmentHandler := handlers.NewDepartmentHandler(departmentRepo)
 	orgAttrHandler := handlers.NewOrganizationAttributeHandler(orgAttrRepo)
+	departmentNameHandler := handlers.NewDepartmentNameHandler(departmentNameRepo)
+	planHandler := handlers.NewReorganizationPlanHandler(planRepo)
+	employeeHandler := handlers.NewEmployeeHandler(employeeRepo, assignmentRepo)
+	assignmentHandler := handlers.NewAssignmentHandler(assignmentRepo)
+	importHandler := handlers.NewImportHandler(importRepo)
+	departmentHeadHandler := handlers.NewDepartmentHeadHandler(departmentHeadRepo)
 
 	// ルーティング
 	api := e.Group("/api")
@@ -52,6 +64,13 @@ func main() {
 	api.PUT("/departments/:id", departmentHandler.Update)
 	api.DELETE("/departments/:id", departmentHandler.Delete)
 
+	// 期間別部門名称のエンドポイント
+	api.GET("/departments/:id/names", departmentNameHandler.GetHistory)
+	api.GET("/department-names/:department_id/:effective_date", departmentNameHandler.GetByID)
+	api.POST("/department-names", departmentNameHandler.Create)
+	api.PUT("/department-names/:department_id/:effective_date", departmentNameHandler.Update)
+	api.DELETE("/department-names/:department_id/:effective_date", departmentNameHandler.Delete)
+
 	// 期間別組織属性のエンドポイント
 	api.GET("/organization-attributes", orgAttrHandler.GetAll)
 	api.GET("/organization-attributes/:department_id/:effective_date", orgAttrHandler.GetByID)
@@ -61,9 +80,64 @@ func main() {
 	
 	// 特定部門の履歴を取得
 	api.GET("/departments/:id/history", orgAttrHandler.GetDepartmentHistory)
+	api.GET("/departments/:id/versions", orgAttrHandler.GetDepartmentVersions)
+	
+	// 特定日付時点の上位部門・配下の部門を取得
+	api.GET("/departments/:id/ancestors", orgAttrHandler.GetAncestors)
+	api.GET("/departments/:id/descendants", orgAttrHandler.GetDescendants)
+	
+	// 部門の廃止
+	api.POST("/departments/:id/abolish", orgAttrHandler.Abolish)
 	
 	// 特定日付時点の組織階層を取得
 	api.GET("/hierarchy", orgAttrHandler.GetHierarchyByDate)
+	api.GET("/hierarchy/tree", orgAttrHandler.GetHierarchyTree)
+	api.GET("/hierarchy/diff", orgAttrHandler.GetHierarchyDiff)
+	api.GET("/hierarchy/export", orgAttrHandler.ExportHierarchy)
+	api.GET("/timeline", orgAttrHandler.GetTimeline)
+
+	// 組織改編計画のエンドポイント
+	api.GET("/reorganization-plans", planHandler.GetAll)
+	api.GET("/reorganization-plans/:id", planHandler.GetByID)
+	api.POST("/reorganization-plans", planHandler.Create)
+	api.PUT("/reorganization-plans/:id", planHandler.Update)
+	api.GET("/reorganization-plans/:id/preview", planHandler.Preview)
+	api.POST("/reorganization-plans/:id/apply", planHandler.Apply)
+	api.POST("/reorganization-plans/:id/cancel", planHandler.Cancel)
+
+	// 社員のエンドポイント
+	api.GET("/employees", employeeHandler.GetAll)
+	api.GET("/employees/:id", employeeHandler.GetByID)
+	api.POST("/employees", employeeHandler.Create)
+	api.PUT("/employees/:id", employeeHandler.Update)
+	api.DELETE("/employees/:id", employeeHandler.Delete)
+	api.GET("/employees/:id/assignments", employeeHandler.GetAssignments)
+
+	// 期間別所属のエンドポイント
+	api.GET("/assignments/:id", assignmentHandler.GetByID)
+	api.POST("/assignments", assignmentHandler.Create)
+	api.PUT("/assignments/:id", assignmentHandler.Update)
+	api.DELETE("/assignments/:id", assignmentHandler.Delete)
+	
+	// 特定日付時点の部門の所属社員・人数を取得
+	api.GET("/departments/:id/members", assignmentHandler.GetMembers)
+	api.GET("/hierarchy/headcount", assignmentHandler.GetHeadcount)
+
+	// 期間別部門長のエンドポイント
+	api.GET("/departments/:id/heads", departmentHeadHandler.GetByDepartment)
+	api.GET("/department-heads/:id", departmentHeadHandler.GetByID)
+	api.POST("/department-heads", departmentHeadHandler.Create)
+	api.PUT("/department-heads/:id", departmentHeadHandler.Update)
+	api.DELETE("/department-heads/:id", departmentHeadHandler.Delete)
+	
+	// 特定日付時点の承認ルートを取得
+	api.GET("/departments/:id/approval-chain", departmentHeadHandler.GetApprovalChain)
+
+	// 組織構造の取り込みとスナップショット
+	api.POST("/import/departments", importHandler.ImportDepartments)
+	api.POST("/import/organization-attributes", importHandler.ImportAttributes)
+	api.GET("/snapshot", importHandler.ExportSnapshot)
+	api.POST("/snapshot", importHandler.ImportSnapshot)
 
 	// ヘルスチェック
 	e.GET("/health", func(c echo.Context) error {
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RelatedDepartment 上位・配下の部門
+type RelatedDepartment struct {
+	DepartmentID       string   `json:"department_id"`
+	DepartmentName     *string  `json:"department_name"`
+	ParentDepartmentID *string  `json:"parent_department_id"`
+	Depth              int      `json:"depth"` // 起点の部門からの階層数（1が直属の上位部門・直下の部門）
+	Path               []string `json:"path"`  // 上位部門は最上位から、配下の部門は起点の部門からの部門ID
+}
+
+// GetAncestors 特定日付時点の上位部門を最上位から直属の上位部門の順で取得
+// 部門がその日付時点に存在しない場合は nil を返す
+func (r *OrganizationAttributeRepository) GetAncestors(departmentID string, date time.Time) ([]RelatedDepartment, error) {
+	return ancestorsAsOf(r.db, departmentID, date)
+}
+
+func ancestorsAsOf(q queryer, departmentID string, date time.Time) ([]RelatedDepartment, error) {
+	found, err := activeAt(q, departmentID, date)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.ancestor_id, n.department_name, oa.parent_department_id, c.depth
+		FROM organization_closure c
+		JOIN organization_closure s ON s.department_id = c.ancestor_id AND s.depth = 0 AND ` + closureAt("s", "$2") + `
+		JOIN organization_attributes oa ON oa.department_id = s.department_id AND oa.effective_date = s.valid_from
+		` + nameAtJoin("n", "c.ancestor_id", "$2") + `
+		WHERE c.department_id = $1 AND c.depth > 0 AND ` + closureAt("c", "$2") + `
+		ORDER BY c.depth DESC`
+
+	rows, err := q.Query(query, departmentID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestors := []RelatedDepartment{}
+	for rows.Next() {
+		var d RelatedDepartment
+		if err := rows.Scan(&d.DepartmentID, &d.DepartmentName, &d.ParentDepartmentID, &d.Depth); err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 最上位から順に並んでいるので、パスは前の部門のパスに自部門を加えたもの
+	var path []string
+	for i := range ancestors {
+		path = append(path, ancestors[i].DepartmentID)
+		ancestors[i].Path = append([]string{}, path...)
+	}
+
+	return ancestors, nil
+}
+
+// GetDescendants 特定日付時点の配下の部門を深さ優先の順で取得
+// maxDepth が0より大きい場合は maxDepth 階層下までに限る
+// 部門がその日付時点に存在しない場合は nil を返す
+func (r *OrganizationAttributeRepository) GetDescendants(departmentID string, date time.Time, maxDepth int) ([]RelatedDepartment, error) {
+	found, err := activeAt(r.db, departmentID, date)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	// パスは起点の部門からその部門までの上位部門を閉包テーブルから集める
+	query := `
+		SELECT c.department_id, n.department_name, oa.parent_department_id, c.depth,
+			ARRAY(
+				SELECT p.ancestor_id
+				FROM organization_closure p
+				WHERE p.department_id = c.department_id AND p.depth <= c.depth AND ` + closureAt("p", "$2") + `
+				ORDER BY p.depth DESC
+			) AS path
+		FROM organization_closure c
+		JOIN organization_closure s ON s.department_id = c.department_id AND s.depth = 0 AND ` + closureAt("s", "$2") + `
+		JOIN organization_attributes oa ON oa.department_id = s.department_id AND oa.effective_date = s.valid_from
+		` + nameAtJoin("n", "c.department_id", "$2") + `
+		WHERE c.ancestor_id = $1 AND c.depth > 0 AND ` + closureAt("c", "$2") + `
+		AND ($3::int = 0 OR c.depth <= $3::int)
+		ORDER BY path`
+
+	rows, err := r.db.Query(query, departmentID, date, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	descendants := []RelatedDepartment{}
+	for rows.Next() {
+		var d RelatedDepartment
+		err := rows.Scan(&d.DepartmentID, &d.DepartmentName, &d.ParentDepartmentID, &d.Depth, pq.Array(&d.Path))
+		if err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, d)
+	}
+
+	return descendants, rows.Err()
+}
+
+// activeAt 部門が特定日付時点で組織に存在するかを返す
+func activeAt(q queryer, departmentID string, date time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM organization_closure c
+			WHERE c.department_id = $1 AND c.depth = 0 AND ` + closureAt("c", "$2") + `
+		)`
+
+	var found bool
+	err := q.QueryRow(query, departmentID, date).Scan(&found)
+	return found, err
+}
+
+// inactivePeriods 各部門が組織に存在しない期間 [valid_from, valid_to)
+// 最初のレコードの発効日より前（レコードがない部門は全期間）と、廃止レコードの発効日から同じ部門の次のレコードの発効日前日まで
+const inactivePeriods = `
+	SELECT d.department_id, '-infinity'::date AS valid_from, COALESCE(MIN(a.effective_date), 'infinity'::date) AS valid_to
+	FROM departments d
+	LEFT JOIN organization_attributes a ON a.department_id = d.department_id
+	GROUP BY d.department_id
+	UNION ALL
+	SELECT department_id, effective_date, next_date
+	FROM (
+		SELECT
+			department_id,
+			effective_date,
+			is_abolished,
+			COALESCE(LEAD(effective_date) OVER (PARTITION BY department_id ORDER BY effective_date), 'infinity'::date) AS next_date
+		FROM organization_attributes
+	) r
+	WHERE is_abolished`
+
+// firstInactiveDate 期間 [from, to]（to が nil の場合は以降ずっと）のうち、部門が組織に存在しない最初の日付を返す
+// 期間中ずっと組織に存在する場合は nil を返す
+func firstInactiveDate(q queryer, departmentID string, from time.Time, to *time.Time) (*time.Time, error) {
+	query := `
+		WITH inactive AS (` + inactivePeriods + `)
+		SELECT GREATEST(valid_from, $2::date)
+		FROM inactive
+		WHERE department_id = $1
+		AND valid_from <= COALESCE($3::date, 'infinity')
+		AND valid_to > $2::date
+		ORDER BY 1
+		LIMIT 1`
+
+	var date time.Time
+	err := q.QueryRow(query, departmentID, from, to).Scan(&date)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
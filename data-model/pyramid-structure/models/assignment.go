@@ -0,0 +1,329 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Assignment 期間別所属（社員の部門への配属）
+// 失効年月日を省略した所属は現在も有効。主務（primary）は社員ごとに同時に1件まで
+type Assignment struct {
+	AssignmentID   int64      `json:"assignment_id"`
+	EmployeeID     string     `json:"employee_id"`
+	EmployeeName   *string    `json:"employee_name,omitempty"` // 導出属性
+	DepartmentID   string     `json:"department_id"`
+	DepartmentName *string    `json:"department_name,omitempty"` // 導出属性
+	EffectiveDate  time.Time  `json:"effective_date"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	Position       *string    `json:"position"`
+	Primary        bool       `json:"primary"` // true: 主務、false: 兼務
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DepartmentHeadcount 特定日付時点の部門の人数
+type DepartmentHeadcount struct {
+	DepartmentID       string  `json:"department_id"`
+	DepartmentName     *string `json:"department_name"`
+	ParentDepartmentID *string `json:"parent_department_id"`
+	Headcount          int     `json:"headcount"`       // 主務として所属する社員数
+	Concurrent         int     `json:"concurrent"`      // 兼務として所属する社員数
+	TotalHeadcount     int     `json:"total_headcount"` // 配下の部門を含めて主務として所属する社員数
+	TotalMembers       int     `json:"total_members"`   // 配下の部門を含めて主務・兼務のいずれかで所属する社員数（重複なし）
+}
+
+type AssignmentRepository struct {
+	db *sql.DB
+}
+
+func NewAssignmentRepository(db *sql.DB) *AssignmentRepository {
+	return &AssignmentRepository{db: db}
+}
+
+// assignmentColumns 部門名は dateExpr の日付時点の名称
+func assignmentColumns(dateExpr string) string {
+	return `
+		a.assignment_id,
+		a.employee_id,
+		e.employee_name,
+		a.department_id,
+		n.department_name,
+		a.effective_date,
+		a.expiration_date,
+		a.position,
+		a.is_primary,
+		a.created_at,
+		a.updated_at
+	FROM employee_assignments a
+	JOIN employees e ON e.employee_id = a.employee_id
+	` + nameAtJoin("n", "a.department_id", dateExpr)
+}
+
+func scanAssignment(row interface{ Scan(...interface{}) error }) (*Assignment, error) {
+	var a Assignment
+	err := row.Scan(&a.AssignmentID, &a.EmployeeID, &a.EmployeeName, &a.DepartmentID, &a.DepartmentName,
+		&a.EffectiveDate, &a.ExpirationDate, &a.Position, &a.Primary, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func scanAssignments(rows *sql.Rows) ([]Assignment, error) {
+	defer rows.Close()
+
+	assignments := []Assignment{}
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, *a)
+	}
+
+	return assignments, rows.Err()
+}
+
+// GetByEmployee 社員の所属の履歴を取得
+func (r *AssignmentRepository) GetByEmployee(employeeID string) ([]Assignment, error) {
+	query := `SELECT ` + assignmentColumns("a.effective_date") + `
+			  WHERE a.employee_id = $1
+			  ORDER BY a.effective_date DESC, a.is_primary DESC, a.department_id`
+
+	rows, err := r.db.Query(query, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAssignments(rows)
+}
+
+func (r *AssignmentRepository) GetByID(assignmentID int64) (*Assignment, error) {
+	query := `SELECT ` + assignmentColumns("a.effective_date") + `
+			  WHERE a.assignment_id = $1`
+
+	a, err := scanAssignment(r.db.QueryRow(query, assignmentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+func (r *AssignmentRepository) Create(a *Assignment) error {
+	query := `INSERT INTO employee_assignments (employee_id, department_id, effective_date, expiration_date, position, is_primary)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING assignment_id, created_at, updated_at`
+
+	return withEmployeeLock(r.db, a.EmployeeID, func(tx *sql.Tx) error {
+		if err := validateAssignment(tx, a); err != nil {
+			return err
+		}
+		return tx.QueryRow(query, a.EmployeeID, a.DepartmentID, a.EffectiveDate, a.ExpirationDate, a.Position, a.Primary).
+			Scan(&a.AssignmentID, &a.CreatedAt, &a.UpdatedAt)
+	})
+}
+
+// Update 所属を更新する（社員は変更できない）
+func (r *AssignmentRepository) Update(a *Assignment) error {
+	query := `UPDATE employee_assignments
+			  SET department_id = $2, effective_date = $3, expiration_date = $4, position = $5, is_primary = $6,
+				  updated_at = CURRENT_TIMESTAMP
+			  WHERE assignment_id = $1
+			  RETURNING updated_at`
+
+	var employeeID string
+	err := r.db.QueryRow(`SELECT employee_id FROM employee_assignments WHERE assignment_id = $1`, a.AssignmentID).
+		Scan(&employeeID)
+	if err != nil {
+		return err
+	}
+	a.EmployeeID = employeeID
+
+	return withEmployeeLock(r.db, a.EmployeeID, func(tx *sql.Tx) error {
+		if err := validateAssignment(tx, a); err != nil {
+			return err
+		}
+		return tx.QueryRow(query, a.AssignmentID, a.DepartmentID, a.EffectiveDate, a.ExpirationDate, a.Position, a.Primary).
+			Scan(&a.UpdatedAt)
+	})
+}
+
+func (r *AssignmentRepository) Delete(assignmentID int64) error {
+	result, err := r.db.Exec(`DELETE FROM employee_assignments WHERE assignment_id = $1`, assignmentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetMembers 特定日付時点で部門に所属する社員を取得
+// includeDescendants が true の場合は配下の部門に所属する社員も含める
+// 部門がその日付時点に存在しない場合は nil を返す
+func (r *AssignmentRepository) GetMembers(departmentID string, date time.Time, includeDescendants bool) ([]Assignment, error) {
+	found, err := activeAt(r.db, departmentID, date)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + assignmentColumns("$2") + `
+		JOIN organization_closure c ON c.department_id = a.department_id AND ` + closureAt("c", "$2") + `
+		WHERE c.ancestor_id = $1
+		AND ($3 OR c.depth = 0)
+		AND a.effective_date <= $2 AND (a.expiration_date IS NULL OR a.expiration_date >= $2)
+		ORDER BY c.depth, a.department_id, a.is_primary DESC, a.employee_id`
+
+	rows, err := r.db.Query(query, departmentID, date, includeDescendants)
+	if err != nil {
+		return nil, err
+	}
+	return scanAssignments(rows)
+}
+
+// GetHeadcount 特定日付時点の部門ごとの人数を、配下の部門への集計を含めて取得
+// rootID を指定した場合はその部門と配下の部門に限る。rootID の部門がその日付時点に存在しない場合は nil を返す
+func (r *AssignmentRepository) GetHeadcount(date time.Time, rootID string) ([]DepartmentHeadcount, error) {
+	if rootID != "" {
+		found, err := activeAt(r.db, rootID, date)
+		if err != nil || !found {
+			return nil, err
+		}
+	}
+
+	// 閉包テーブルで各部門に自部門と配下の部門の所属を結び付けて集計する
+	query := `
+		WITH current_assignments AS (
+			SELECT employee_id, department_id, is_primary
+			FROM employee_assignments
+			WHERE effective_date <= $1 AND (expiration_date IS NULL OR expiration_date >= $1)
+		)
+		SELECT
+			s.department_id,
+			n.department_name,
+			oa.parent_department_id,
+			COUNT(DISTINCT ca.employee_id) FILTER (WHERE c.depth = 0 AND ca.is_primary),
+			COUNT(DISTINCT ca.employee_id) FILTER (WHERE c.depth = 0 AND NOT ca.is_primary),
+			COUNT(DISTINCT ca.employee_id) FILTER (WHERE ca.is_primary),
+			COUNT(DISTINCT ca.employee_id)
+		FROM organization_closure s
+		JOIN organization_attributes oa ON oa.department_id = s.department_id AND oa.effective_date = s.valid_from
+		JOIN organization_closure c ON c.ancestor_id = s.department_id AND ` + closureAt("c", "$1") + `
+		LEFT JOIN current_assignments ca ON ca.department_id = c.department_id
+		` + nameAtJoin("n", "s.department_id", "$1") + `
+		WHERE s.depth = 0 AND ` + closureAt("s", "$1") + `
+		AND ($2::text = '' OR EXISTS (
+			SELECT 1 FROM organization_closure r
+			WHERE r.department_id = s.department_id AND r.ancestor_id = $2::text AND ` + closureAt("r", "$1") + `
+		))
+		GROUP BY s.department_id, n.department_name, oa.parent_department_id
+		ORDER BY s.department_id`
+
+	rows, err := r.db.Query(query, date, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	headcounts := []DepartmentHeadcount{}
+	for rows.Next() {
+		var h DepartmentHeadcount
+		err := rows.Scan(&h.DepartmentID, &h.DepartmentName, &h.ParentDepartmentID,
+			&h.Headcount, &h.Concurrent, &h.TotalHeadcount, &h.TotalMembers)
+		if err != nil {
+			return nil, err
+		}
+		headcounts = append(headcounts, h)
+	}
+
+	return headcounts, rows.Err()
+}
+
+// withEmployeeLock 社員の行をロックしてトランザクションを実行する
+// 同じ社員の所属の同時変更で、主務の重複などが生じないようにする
+func withEmployeeLock(db *sql.DB, employeeID string, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked string
+	err = tx.QueryRow(`SELECT employee_id FROM employees WHERE employee_id = $1 FOR UPDATE`, employeeID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: fmt.Sprintf("Employee %s does not exist", employeeID)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// validateAssignment 所属の期間と部門を検証する
+// 部門は所属の期間全体で組織に存在し、同じ社員の同じ部門への所属・主務は期間が重ならないこと
+func validateAssignment(tx *sql.Tx, a *Assignment) error {
+	if a.ExpirationDate != nil && a.ExpirationDate.Before(a.EffectiveDate) {
+		return &ValidationError{Message: "Expiration date must not be before effective date"}
+	}
+
+	found, err := activeAt(tx, a.DepartmentID, a.EffectiveDate)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &ValidationError{Message: fmt.Sprintf("Department %s is not part of the organization on %s",
+			a.DepartmentID, a.EffectiveDate.Format("2006-01-02"))}
+	}
+
+	inactiveDate, err := firstInactiveDate(tx, a.DepartmentID, a.EffectiveDate, a.ExpirationDate)
+	if err != nil {
+		return err
+	}
+	if inactiveDate != nil {
+		return &ValidationError{Message: fmt.Sprintf("Department %s is not part of the organization on %s, within the assignment period",
+			a.DepartmentID, inactiveDate.Format("2006-01-02"))}
+	}
+
+	query := `
+		SELECT department_id, is_primary, effective_date
+		FROM employee_assignments
+		WHERE employee_id = $1 AND assignment_id <> $2
+		AND (department_id = $3 OR ($4 AND is_primary))
+		AND effective_date <= COALESCE($6::date, 'infinity')
+		AND COALESCE(expiration_date, 'infinity') >= $5::date
+		ORDER BY effective_date
+		LIMIT 1`
+
+	var departmentID string
+	var primary bool
+	var effectiveDate time.Time
+	err = tx.QueryRow(query, a.EmployeeID, a.AssignmentID, a.DepartmentID, a.Primary, a.EffectiveDate, a.ExpirationDate).
+		Scan(&departmentID, &primary, &effectiveDate)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if departmentID == a.DepartmentID {
+		return &ValidationError{Message: fmt.Sprintf("Employee %s is already assigned to %s in an overlapping period (from %s)",
+			a.EmployeeID, departmentID, effectiveDate.Format("2006-01-02"))}
+	}
+	return &ValidationError{Message: fmt.Sprintf("Employee %s already has a primary assignment to %s in an overlapping period (from %s)",
+		a.EmployeeID, departmentID, effectiveDate.Format("2006-01-02"))}
+}
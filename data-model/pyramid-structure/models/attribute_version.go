@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+)
+
+// 組織属性の記録時点の履歴（organization_attribute_versions）
+// 組織属性の作成・更新・削除のたびにトリガーで記録され、ある時点でシステムに登録されていた内容を再現できる
+// 有効時点（発効年月日）と記録時点（recorded_at / superseded_at）の二つの時間軸を持つ
+
+// AttributeVersion 組織属性の記録時点の版
+type AttributeVersion struct {
+	VersionID          int64      `json:"version_id"`
+	DepartmentID       string     `json:"department_id"`
+	EffectiveDate      time.Time  `json:"effective_date"`
+	ParentDepartmentID *string    `json:"parent_department_id"`
+	Abolished          bool       `json:"abolished"`
+	RecordedAt         time.Time  `json:"recorded_at"`   // この版が登録された日時
+	SupersededAt       *time.Time `json:"superseded_at"` // この版が更新・削除された日時（NULLの場合は現在の内容）
+}
+
+// knownAttributes knownAtExpr の日時にシステムに登録されていた組織属性
+func knownAttributes(knownAtExpr string) string {
+	return `
+		SELECT department_id, effective_date, parent_department_id, is_abolished, created_at, updated_at, recorded_at
+		FROM organization_attribute_versions
+		WHERE recorded_at <= ` + knownAtExpr + `
+		AND (superseded_at IS NULL OR superseded_at > ` + knownAtExpr + `)`
+}
+
+// GetDepartmentVersions 部門の組織属性の記録時点の履歴を取得
+func (r *OrganizationAttributeRepository) GetDepartmentVersions(departmentID string) ([]AttributeVersion, error) {
+	query := `
+		SELECT version_id, department_id, effective_date, parent_department_id, is_abolished, recorded_at, superseded_at
+		FROM organization_attribute_versions
+		WHERE department_id = $1
+		ORDER BY effective_date DESC, recorded_at DESC, version_id DESC`
+
+	rows, err := r.db.Query(query, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []AttributeVersion{}
+	for rows.Next() {
+		var v AttributeVersion
+		err := rows.Scan(&v.VersionID, &v.DepartmentID, &v.EffectiveDate, &v.ParentDepartmentID, &v.Abolished,
+			&v.RecordedAt, &v.SupersededAt)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetDepartmentHistoryAsKnownAt knownAt の日時にシステムに登録されていた部門の履歴を取得
+func (r *OrganizationAttributeRepository) GetDepartmentHistoryAsKnownAt(departmentID string, knownAt time.Time) ([]OrganizationAttribute, error) {
+	query := `
+		WITH known AS (` + knownAttributes("$2") + `)
+		SELECT
+			k.department_id,
+			k.effective_date,
+			k.parent_department_id,
+			k.is_abolished,
+			(
+				SELECT MIN(k2.effective_date) - INTERVAL '1 day'
+				FROM known k2
+				WHERE k2.department_id = k.department_id
+				AND k2.effective_date > k.effective_date
+			) AS expiration_date,
+			n.department_name,
+			pn.department_name,
+			k.created_at,
+			k.updated_at,
+			k.recorded_at
+		FROM known k
+		` + nameAtJoin("n", "k.department_id", "k.effective_date") + `
+		` + nameAtJoin("pn", "k.parent_department_id", "k.effective_date") + `
+		WHERE k.department_id = $1
+		ORDER BY k.effective_date DESC`
+
+	return r.queryKnownAttributes(query, departmentID, knownAt)
+}
+
+// GetHierarchyAsKnownAt knownAt の日時にシステムに登録されていた内容による、特定日付時点の組織階層を取得
+// 閉包テーブルは現在の内容のみを表すため、記録時点の履歴から組み立てる
+func (r *OrganizationAttributeRepository) GetHierarchyAsKnownAt(targetDate, knownAt time.Time) ([]OrganizationAttribute, error) {
+	query := `
+		WITH known AS (` + knownAttributes("$2") + `),
+		latest_attrs AS (
+			SELECT DISTINCT ON (department_id) *
+			FROM known
+			WHERE effective_date <= $1
+			ORDER BY department_id, effective_date DESC
+		)
+		SELECT
+			la.department_id,
+			la.effective_date,
+			la.parent_department_id,
+			la.is_abolished,
+			(
+				SELECT MIN(k.effective_date) - INTERVAL '1 day'
+				FROM known k
+				WHERE k.department_id = la.department_id
+				AND k.effective_date > la.effective_date
+			) AS expiration_date,
+			n.department_name,
+			pn.department_name,
+			la.created_at,
+			la.updated_at,
+			la.recorded_at
+		FROM latest_attrs la
+		` + nameAtJoin("n", "la.department_id", "$1") + `
+		` + nameAtJoin("pn", "la.parent_department_id", "$1") + `
+		WHERE NOT la.is_abolished
+		ORDER BY la.department_id`
+
+	return r.queryKnownAttributes(query, targetDate, knownAt)
+}
+
+func (r *OrganizationAttributeRepository) queryKnownAttributes(query string, args ...interface{}) ([]OrganizationAttribute, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attrs := []OrganizationAttribute{}
+	for rows.Next() {
+		var a OrganizationAttribute
+		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, &a.Abolished,
+			&a.ExpirationDate, &a.DepartmentName, &a.ParentDepartmentName, &a.CreatedAt, &a.UpdatedAt, &a.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+	}
+
+	return attrs, rows.Err()
+}
+
+// hierarchy knownAt が nil の場合は現在の内容、それ以外はその日時に登録されていた内容による組織階層を取得
+func (r *OrganizationAttributeRepository) hierarchy(targetDate time.Time, knownAt *time.Time) ([]OrganizationAttribute, error) {
+	if knownAt != nil {
+		return r.GetHierarchyAsKnownAt(targetDate, *knownAt)
+	}
+	return r.GetHierarchyByDate(targetDate)
+}
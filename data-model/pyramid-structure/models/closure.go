@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// 組織階層閉包テーブル（organization_closure）
+// 組織属性から導出したテーブルで、階層の読み取りはこのテーブルを使う
+// 組織属性を変更するトランザクションの中で refreshClosure を呼び、同じトランザクションで更新する
+
+// refreshClosure 変更された部門とその配下の部門の閉包テーブルの行を作り直す
+func refreshClosure(tx *sql.Tx, departmentIDs ...string) error {
+	if len(departmentIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`SELECT refresh_organization_closure($1)`, pq.Array(departmentIDs))
+	return err
+}
+
+// RebuildClosure 閉包テーブルをすべての部門について作り直す
+// 組織属性を SQL で直接変更した後などに使う
+func (r *OrganizationAttributeRepository) RebuildClosure() error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`SELECT refresh_organization_closure(NULL)`)
+		return err
+	})
+}
+
+// closureAt 閉包テーブルの行が dateExpr の日付時点で有効である条件
+func closureAt(alias, dateExpr string) string {
+	return alias + `.valid_from <= ` + dateExpr + ` AND (` + alias + `.valid_to IS NULL OR ` + alias + `.valid_to >= ` + dateExpr + `)`
+}
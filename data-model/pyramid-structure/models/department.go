@@ -7,7 +7,8 @@ import (
 
 type Department struct {
 	DepartmentID   string    `json:"department_id"`
-	DepartmentName string    `json:"department_name"`
+	DepartmentName string    `json:"department_name"` // 現在有効な部門名（department_names から導出）
+	ShortName      *string   `json:"short_name"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -21,8 +22,11 @@ func NewDepartmentRepository(db *sql.DB) *DepartmentRepository {
 }
 
 func (r *DepartmentRepository) GetAll() ([]Department, error) {
-	query := `SELECT department_id, department_name, created_at, updated_at 
-			  FROM departments ORDER BY department_id`
+	query := `SELECT d.department_id, COALESCE(n.department_name, d.department_id), n.short_name, 
+			  d.created_at, d.updated_at 
+			  FROM departments d
+			  ` + nameAtJoin("n", "d.department_id", "CURRENT_DATE") + `
+			  ORDER BY d.department_id`
 	
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -33,7 +37,7 @@ func (r *DepartmentRepository) GetAll() ([]Department, error) {
 	var departments []Department
 	for rows.Next() {
 		var d Department
-		err := rows.Scan(&d.DepartmentID, &d.DepartmentName, &d.CreatedAt, &d.UpdatedAt)
+		err := rows.Scan(&d.DepartmentID, &d.DepartmentName, &d.ShortName, &d.CreatedAt, &d.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -44,11 +48,14 @@ func (r *DepartmentRepository) GetAll() ([]Department, error) {
 }
 
 func (r *DepartmentRepository) GetByID(id string) (*Department, error) {
-	query := `SELECT department_id, department_name, created_at, updated_at 
-			  FROM departments WHERE department_id = $1`
+	query := `SELECT d.department_id, COALESCE(n.department_name, d.department_id), n.short_name, 
+			  d.created_at, d.updated_at 
+			  FROM departments d
+			  ` + nameAtJoin("n", "d.department_id", "CURRENT_DATE") + `
+			  WHERE d.department_id = $1`
 	
 	var d Department
-	err := r.db.QueryRow(query, id).Scan(&d.DepartmentID, &d.DepartmentName, &d.CreatedAt, &d.UpdatedAt)
+	err := r.db.QueryRow(query, id).Scan(&d.DepartmentID, &d.DepartmentName, &d.ShortName, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -59,26 +66,82 @@ func (r *DepartmentRepository) GetByID(id string) (*Department, error) {
 	return &d, nil
 }
 
+// Create 部門と、本日から有効な部門名称を登録する
 func (r *DepartmentRepository) Create(d *Department) error {
-	query := `INSERT INTO departments (department_id, department_name) 
-			  VALUES ($1, $2) 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	query := `INSERT INTO departments (department_id) 
+			  VALUES ($1) 
 			  RETURNING created_at, updated_at`
 	
-	err := r.db.QueryRow(query, d.DepartmentID, d.DepartmentName).Scan(&d.CreatedAt, &d.UpdatedAt)
-	return err
+	if err := tx.QueryRow(query, d.DepartmentID).Scan(&d.CreatedAt, &d.UpdatedAt); err != nil {
+		return err
+	}
+	
+	_, err = tx.Exec(`INSERT INTO department_names (department_id, effective_date, department_name, short_name) 
+			  VALUES ($1, CURRENT_DATE, $2, $3)`, d.DepartmentID, d.DepartmentName, d.ShortName)
+	if err != nil {
+		return err
+	}
+	
+	return tx.Commit()
 }
 
+// Update 現在有効な部門名称を訂正する（改称は期間別部門名称として登録する）
 func (r *DepartmentRepository) Update(d *Department) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
 	query := `UPDATE departments 
-			  SET department_name = $2, updated_at = CURRENT_TIMESTAMP 
+			  SET updated_at = CURRENT_TIMESTAMP 
 			  WHERE department_id = $1 
 			  RETURNING updated_at`
 	
-	err := r.db.QueryRow(query, d.DepartmentID, d.DepartmentName).Scan(&d.UpdatedAt)
+	err = tx.QueryRow(query, d.DepartmentID).Scan(&d.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	
+	result, err := tx.Exec(`UPDATE department_names 
+			  SET department_name = $2, short_name = $3, updated_at = CURRENT_TIMESTAMP 
+			  WHERE department_id = $1 
+			  AND effective_date = (
+				  SELECT dn.effective_date FROM department_names dn
+				  WHERE dn.department_id = $1
+				  ORDER BY dn.effective_date > CURRENT_DATE,
+					  CASE WHEN dn.effective_date <= CURRENT_DATE THEN dn.effective_date END DESC NULLS LAST,
+					  dn.effective_date
+				  LIMIT 1
+			  )`, d.DepartmentID, d.DepartmentName, d.ShortName)
+	if err != nil {
+		return err
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	
+	// 名称が1件もない部門には本日から有効な名称を登録する
+	if rowsAffected == 0 {
+		_, err = tx.Exec(`INSERT INTO department_names (department_id, effective_date, department_name, short_name) 
+				  VALUES ($1, CURRENT_DATE, $2, $3)`, d.DepartmentID, d.DepartmentName, d.ShortName)
+		if err != nil {
+			return err
+		}
+	}
+	
+	return tx.Commit()
 }
 
 func (r *DepartmentRepository) Delete(id string) error {
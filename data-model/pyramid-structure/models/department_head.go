@@ -0,0 +1,295 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DepartmentHead 期間別部門長
+// 失効年月日を省略した部門長は現在も有効。部門長は部門ごとに同時に1人まで
+type DepartmentHead struct {
+	HeadID         int64      `json:"head_id"`
+	DepartmentID   string     `json:"department_id"`
+	DepartmentName *string    `json:"department_name,omitempty"` // 導出属性
+	EmployeeID     string     `json:"employee_id"`
+	EmployeeName   *string    `json:"employee_name,omitempty"` // 導出属性
+	EffectiveDate  time.Time  `json:"effective_date"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ApprovalStep 承認ルート上の部門とその部門長
+type ApprovalStep struct {
+	DepartmentID   string  `json:"department_id"`
+	DepartmentName *string `json:"department_name"`
+	Depth          int     `json:"depth"` // 起点の部門からの階層数（0が起点の部門、1が直属の上位部門）
+	EmployeeID     *string `json:"employee_id"`
+	EmployeeName   *string `json:"employee_name"`
+}
+
+// ApprovalChain 特定日付時点の承認ルート
+type ApprovalChain struct {
+	DepartmentID string         `json:"department_id"`
+	Date         string         `json:"date"`
+	Approvers    []ApprovalStep `json:"approvers"` // 起点の部門から最上位に向かう順の承認者
+	Vacancies    []ApprovalStep `json:"vacancies"` // 部門長が空席のため飛ばした部門
+}
+
+type DepartmentHeadRepository struct {
+	db *sql.DB
+}
+
+func NewDepartmentHeadRepository(db *sql.DB) *DepartmentHeadRepository {
+	return &DepartmentHeadRepository{db: db}
+}
+
+// departmentHeadColumns 部門名は部門長の発効日時点の名称
+var departmentHeadColumns = `
+		h.head_id,
+		h.department_id,
+		n.department_name,
+		h.employee_id,
+		e.employee_name,
+		h.effective_date,
+		h.expiration_date,
+		h.created_at,
+		h.updated_at
+	FROM department_heads h
+	JOIN employees e ON e.employee_id = h.employee_id
+	` + nameAtJoin("n", "h.department_id", "h.effective_date")
+
+func scanDepartmentHead(row interface{ Scan(...interface{}) error }) (*DepartmentHead, error) {
+	var h DepartmentHead
+	err := row.Scan(&h.HeadID, &h.DepartmentID, &h.DepartmentName, &h.EmployeeID, &h.EmployeeName,
+		&h.EffectiveDate, &h.ExpirationDate, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// GetByDepartment 部門の部門長の履歴を取得
+func (r *DepartmentHeadRepository) GetByDepartment(departmentID string) ([]DepartmentHead, error) {
+	query := `SELECT ` + departmentHeadColumns + `
+			  WHERE h.department_id = $1
+			  ORDER BY h.effective_date DESC`
+
+	rows, err := r.db.Query(query, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heads := []DepartmentHead{}
+	for rows.Next() {
+		h, err := scanDepartmentHead(rows)
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, *h)
+	}
+
+	return heads, rows.Err()
+}
+
+func (r *DepartmentHeadRepository) GetByID(headID int64) (*DepartmentHead, error) {
+	query := `SELECT ` + departmentHeadColumns + `
+			  WHERE h.head_id = $1`
+
+	h, err := scanDepartmentHead(r.db.QueryRow(query, headID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (r *DepartmentHeadRepository) Create(h *DepartmentHead) error {
+	query := `INSERT INTO department_heads (department_id, employee_id, effective_date, expiration_date)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING head_id, created_at, updated_at`
+
+	return withDepartmentLock(r.db, h.DepartmentID, func(tx *sql.Tx) error {
+		if err := validateDepartmentHead(tx, h); err != nil {
+			return err
+		}
+		return tx.QueryRow(query, h.DepartmentID, h.EmployeeID, h.EffectiveDate, h.ExpirationDate).
+			Scan(&h.HeadID, &h.CreatedAt, &h.UpdatedAt)
+	})
+}
+
+// Update 部門長を更新する（部門は変更できない）
+func (r *DepartmentHeadRepository) Update(h *DepartmentHead) error {
+	query := `UPDATE department_heads
+			  SET employee_id = $2, effective_date = $3, expiration_date = $4, updated_at = CURRENT_TIMESTAMP
+			  WHERE head_id = $1
+			  RETURNING created_at, updated_at`
+
+	var departmentID string
+	err := r.db.QueryRow(`SELECT department_id FROM department_heads WHERE head_id = $1`, h.HeadID).Scan(&departmentID)
+	if err != nil {
+		return err
+	}
+	h.DepartmentID = departmentID
+
+	return withDepartmentLock(r.db, h.DepartmentID, func(tx *sql.Tx) error {
+		if err := validateDepartmentHead(tx, h); err != nil {
+			return err
+		}
+		return tx.QueryRow(query, h.HeadID, h.EmployeeID, h.EffectiveDate, h.ExpirationDate).
+			Scan(&h.CreatedAt, &h.UpdatedAt)
+	})
+}
+
+func (r *DepartmentHeadRepository) Delete(headID int64) error {
+	result, err := r.db.Exec(`DELETE FROM department_heads WHERE head_id = $1`, headID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetApprovalChain 特定日付時点の承認ルートを取得
+// 起点の部門から上位部門をたどり、その日付時点の部門長を順に集める。部門長が空席の部門は飛ばす
+// 同じ社員が複数の部門の部門長を兼ねる場合は最初の部門でのみ承認者とし、requesterID の社員（申請者本人）は承認者に含めない
+// 部門がその日付時点に存在しない場合は nil を返す
+func (r *DepartmentHeadRepository) GetApprovalChain(departmentID string, date time.Time, requesterID string) (*ApprovalChain, error) {
+	found, err := activeAt(r.db, departmentID, date)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.ancestor_id, n.department_name, c.depth, h.employee_id, e.employee_name
+		FROM organization_closure c
+		LEFT JOIN department_heads h ON h.department_id = c.ancestor_id
+			AND h.effective_date <= $2 AND (h.expiration_date IS NULL OR h.expiration_date >= $2)
+		LEFT JOIN employees e ON e.employee_id = h.employee_id
+		` + nameAtJoin("n", "c.ancestor_id", "$2") + `
+		WHERE c.department_id = $1 AND ` + closureAt("c", "$2") + `
+		ORDER BY c.depth`
+
+	rows, err := r.db.Query(query, departmentID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chain := &ApprovalChain{
+		DepartmentID: departmentID,
+		Date:         date.Format("2006-01-02"),
+		Approvers:    []ApprovalStep{},
+		Vacancies:    []ApprovalStep{},
+	}
+	seen := map[string]bool{requesterID: true}
+	for rows.Next() {
+		var s ApprovalStep
+		if err := rows.Scan(&s.DepartmentID, &s.DepartmentName, &s.Depth, &s.EmployeeID, &s.EmployeeName); err != nil {
+			return nil, err
+		}
+		if s.EmployeeID == nil {
+			chain.Vacancies = append(chain.Vacancies, s)
+			continue
+		}
+		if seen[*s.EmployeeID] {
+			continue
+		}
+		seen[*s.EmployeeID] = true
+		chain.Approvers = append(chain.Approvers, s)
+	}
+
+	return chain, rows.Err()
+}
+
+// withDepartmentLock 部門の行をロックしてトランザクションを実行する
+// 同じ部門の部門長の同時変更で、期間の重複が生じないようにする
+func withDepartmentLock(db *sql.DB, departmentID string, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked string
+	err = tx.QueryRow(`SELECT department_id FROM departments WHERE department_id = $1 FOR UPDATE`, departmentID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return &ValidationError{Message: fmt.Sprintf("Department %s does not exist", departmentID)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// validateDepartmentHead 部門長の期間と社員を検証する
+// 部門は部門長の期間全体で組織に存在し、同じ部門の部門長は期間が重ならないこと
+func validateDepartmentHead(tx *sql.Tx, h *DepartmentHead) error {
+	if h.ExpirationDate != nil && h.ExpirationDate.Before(h.EffectiveDate) {
+		return &ValidationError{Message: "Expiration date must not be before effective date"}
+	}
+
+	var employeeExists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM employees WHERE employee_id = $1)`, h.EmployeeID).Scan(&employeeExists)
+	if err != nil {
+		return err
+	}
+	if !employeeExists {
+		return &ValidationError{Message: fmt.Sprintf("Employee %s does not exist", h.EmployeeID)}
+	}
+
+	found, err := activeAt(tx, h.DepartmentID, h.EffectiveDate)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &ValidationError{Message: fmt.Sprintf("Department %s is not part of the organization on %s",
+			h.DepartmentID, h.EffectiveDate.Format("2006-01-02"))}
+	}
+
+	inactiveDate, err := firstInactiveDate(tx, h.DepartmentID, h.EffectiveDate, h.ExpirationDate)
+	if err != nil {
+		return err
+	}
+	if inactiveDate != nil {
+		return &ValidationError{Message: fmt.Sprintf("Department %s is not part of the organization on %s, within the head's period",
+			h.DepartmentID, inactiveDate.Format("2006-01-02"))}
+	}
+
+	query := `
+		SELECT employee_id, effective_date
+		FROM department_heads
+		WHERE department_id = $1 AND head_id <> $2
+		AND effective_date <= COALESCE($4::date, 'infinity')
+		AND COALESCE(expiration_date, 'infinity') >= $3::date
+		ORDER BY effective_date
+		LIMIT 1`
+
+	var employeeID string
+	var effectiveDate time.Time
+	err = tx.QueryRow(query, h.DepartmentID, h.HeadID, h.EffectiveDate, h.ExpirationDate).Scan(&employeeID, &effectiveDate)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &ValidationError{Message: fmt.Sprintf("Department %s already has a head (%s) in an overlapping period (from %s)",
+		h.DepartmentID, employeeID, effectiveDate.Format("2006-01-02"))}
+}
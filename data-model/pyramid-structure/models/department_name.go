@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DepartmentName 期間別部門名称
+type DepartmentName struct {
+	DepartmentID   string     `json:"department_id"`
+	EffectiveDate  time.Time  `json:"effective_date"`
+	DepartmentName string     `json:"department_name"`
+	ShortName      *string    `json:"short_name"`
+	ExpirationDate *time.Time `json:"expiration_date"` // 導出属性
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type DepartmentNameRepository struct {
+	db *sql.DB
+}
+
+func NewDepartmentNameRepository(db *sql.DB) *DepartmentNameRepository {
+	return &DepartmentNameRepository{db: db}
+}
+
+// nameAtJoin 部門ID deptExpr の、日付 dateExpr 時点で有効な名称を alias として結合する
+// その日付より前に名称がない場合は最初の名称を使う
+func nameAtJoin(alias, deptExpr, dateExpr string) string {
+	return `LEFT JOIN LATERAL (
+			SELECT dn.department_name, dn.short_name
+			FROM department_names dn
+			WHERE dn.department_id = ` + deptExpr + `
+			ORDER BY dn.effective_date > ` + dateExpr + `,
+				CASE WHEN dn.effective_date <= ` + dateExpr + ` THEN dn.effective_date END DESC NULLS LAST,
+				dn.effective_date
+			LIMIT 1
+		) ` + alias + ` ON TRUE`
+}
+
+const departmentNameColumns = `
+	dn1.department_id,
+	dn1.effective_date,
+	dn1.department_name,
+	dn1.short_name,
+	(
+		SELECT MIN(dn2.effective_date) - INTERVAL '1 day'
+		FROM department_names dn2
+		WHERE dn2.department_id = dn1.department_id
+		AND dn2.effective_date > dn1.effective_date
+	) AS expiration_date,
+	dn1.created_at,
+	dn1.updated_at`
+
+func scanDepartmentName(row interface{ Scan(...interface{}) error }) (*DepartmentName, error) {
+	var n DepartmentName
+	err := row.Scan(&n.DepartmentID, &n.EffectiveDate, &n.DepartmentName, &n.ShortName,
+		&n.ExpirationDate, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *DepartmentNameRepository) GetHistory(departmentID string) ([]DepartmentName, error) {
+	query := `SELECT ` + departmentNameColumns + `
+			  FROM department_names dn1
+			  WHERE dn1.department_id = $1
+			  ORDER BY dn1.effective_date DESC`
+
+	rows, err := r.db.Query(query, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []DepartmentName{}
+	for rows.Next() {
+		n, err := scanDepartmentName(rows)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, *n)
+	}
+
+	return names, rows.Err()
+}
+
+func (r *DepartmentNameRepository) GetByID(departmentID string, effectiveDate time.Time) (*DepartmentName, error) {
+	query := `SELECT ` + departmentNameColumns + `
+			  FROM department_names dn1
+			  WHERE dn1.department_id = $1 AND dn1.effective_date = $2`
+
+	n, err := scanDepartmentName(r.db.QueryRow(query, departmentID, effectiveDate))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return n, err
+}
+
+func (r *DepartmentNameRepository) Create(n *DepartmentName) error {
+	query := `INSERT INTO department_names (department_id, effective_date, department_name, short_name)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING created_at, updated_at`
+
+	return r.db.QueryRow(query, n.DepartmentID, n.EffectiveDate, n.DepartmentName, n.ShortName).
+		Scan(&n.CreatedAt, &n.UpdatedAt)
+}
+
+func (r *DepartmentNameRepository) Update(n *DepartmentName) error {
+	query := `UPDATE department_names
+			  SET department_name = $3, short_name = $4, updated_at = CURRENT_TIMESTAMP
+			  WHERE department_id = $1 AND effective_date = $2
+			  RETURNING updated_at`
+
+	err := r.db.QueryRow(query, n.DepartmentID, n.EffectiveDate, n.DepartmentName, n.ShortName).
+		Scan(&n.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// Delete 期間別部門名称を削除する。部門には少なくとも1件の名称が必要なため、最後の1件は削除できない
+func (r *DepartmentNameRepository) Delete(departmentID string, effectiveDate time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 同じ部門の名称の同時削除で0件にならないよう、部門の行をロックしてから件数を数える
+	if _, err := tx.Exec(`SELECT 1 FROM departments WHERE department_id = $1 FOR UPDATE`, departmentID); err != nil {
+		return err
+	}
+
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM department_names WHERE department_id = $1`, departmentID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM department_names WHERE department_id = $1 AND effective_date = $2`,
+		departmentID, effectiveDate)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if count <= 1 {
+		return &ValidationError{Message: "A department must have at least one name record"}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Employee 社員
+type Employee struct {
+	EmployeeID   string    `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type EmployeeRepository struct {
+	db *sql.DB
+}
+
+func NewEmployeeRepository(db *sql.DB) *EmployeeRepository {
+	return &EmployeeRepository{db: db}
+}
+
+func (r *EmployeeRepository) GetAll() ([]Employee, error) {
+	query := `SELECT employee_id, employee_name, created_at, updated_at
+			  FROM employees
+			  ORDER BY employee_id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	employees := []Employee{}
+	for rows.Next() {
+		var e Employee
+		if err := rows.Scan(&e.EmployeeID, &e.EmployeeName, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		employees = append(employees, e)
+	}
+
+	return employees, rows.Err()
+}
+
+func (r *EmployeeRepository) GetByID(id string) (*Employee, error) {
+	query := `SELECT employee_id, employee_name, created_at, updated_at
+			  FROM employees
+			  WHERE employee_id = $1`
+
+	var e Employee
+	err := r.db.QueryRow(query, id).Scan(&e.EmployeeID, &e.EmployeeName, &e.CreatedAt, &e.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (r *EmployeeRepository) Create(e *Employee) error {
+	query := `INSERT INTO employees (employee_id, employee_name)
+			  VALUES ($1, $2)
+			  RETURNING created_at, updated_at`
+
+	return r.db.QueryRow(query, e.EmployeeID, e.EmployeeName).Scan(&e.CreatedAt, &e.UpdatedAt)
+}
+
+func (r *EmployeeRepository) Update(e *Employee) error {
+	query := `UPDATE employees
+			  SET employee_name = $2, updated_at = CURRENT_TIMESTAMP
+			  WHERE employee_id = $1
+			  RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(query, e.EmployeeID, e.EmployeeName).Scan(&e.CreatedAt, &e.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// Delete 社員を削除する（所属の履歴も削除される）
+func (r *EmployeeRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM employees WHERE employee_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
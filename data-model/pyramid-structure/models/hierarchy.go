@@ -0,0 +1,108 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// HierarchyNode 組織階層ツリーのノード
+type HierarchyNode struct {
+	DepartmentID       string           `json:"department_id"`
+	DepartmentName     string           `json:"department_name"`
+	ParentDepartmentID *string          `json:"parent_department_id"`
+	EffectiveDate      time.Time        `json:"effective_date"`
+	ExpirationDate     *time.Time       `json:"expiration_date"`
+	Depth              int              `json:"depth"`     // 最上位を0とする階層の深さ
+	Path               []string         `json:"path"`      // 最上位から自部門までの部門ID
+	FullPath           string           `json:"full_path"` // 最上位から自部門までの部門名
+	Children           []*HierarchyNode `json:"children"`
+}
+
+const fullPathSeparator = " / "
+
+// BuildHierarchyTree 特定日付時点の組織属性からツリーを構築する
+// 部門名は組織属性に設定された基準日時点の名称を使う
+// 上位部門がその日付時点に存在しない部門は最上位として扱う
+// rootID を指定した場合はその部門を起点とし、maxDepth が0以上の場合は起点から maxDepth 階層下までを含める
+// rootID の部門が存在しない場合は nil を返す
+func BuildHierarchyTree(attrs []OrganizationAttribute, rootID string, maxDepth int) []*HierarchyNode {
+	nodes := make(map[string]*HierarchyNode, len(attrs))
+	for _, a := range attrs {
+		nodes[a.DepartmentID] = &HierarchyNode{
+			DepartmentID:       a.DepartmentID,
+			DepartmentName:     a.Name(),
+			ParentDepartmentID: a.ParentDepartmentID,
+			EffectiveDate:      a.EffectiveDate,
+			ExpirationDate:     a.ExpirationDate,
+			Children:           []*HierarchyNode{},
+		}
+	}
+
+	var roots []*HierarchyNode
+	for _, a := range attrs {
+		node := nodes[a.DepartmentID]
+		if a.ParentDepartmentID != nil {
+			if parent, ok := nodes[*a.ParentDepartmentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortNodes(roots)
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+
+	// 深さとパスは最上位から順に設定する（循環している部門には到達しない）
+	var assign func(node *HierarchyNode, depth int, path []string, pathNames []string)
+	assign = func(node *HierarchyNode, depth int, path []string, pathNames []string) {
+		node.Depth = depth
+		node.Path = append(append([]string{}, path...), node.DepartmentID)
+		pathNames = append(append([]string{}, pathNames...), node.DepartmentName)
+		node.FullPath = strings.Join(pathNames, fullPathSeparator)
+		for _, child := range node.Children {
+			assign(child, depth+1, node.Path, pathNames)
+		}
+	}
+	for _, root := range roots {
+		assign(root, 0, nil, nil)
+	}
+
+	if rootID != "" {
+		root, ok := nodes[rootID]
+		if !ok || root.Path == nil {
+			return nil
+		}
+		roots = []*HierarchyNode{root}
+	}
+
+	if maxDepth >= 0 {
+		for _, root := range roots {
+			pruneHierarchy(root, root.Depth+maxDepth)
+		}
+	}
+
+	if roots == nil {
+		roots = []*HierarchyNode{}
+	}
+	return roots
+}
+
+func pruneHierarchy(node *HierarchyNode, maxDepth int) {
+	if node.Depth >= maxDepth {
+		node.Children = []*HierarchyNode{}
+		return
+	}
+	for _, child := range node.Children {
+		pruneHierarchy(child, maxDepth)
+	}
+}
+
+func sortNodes(nodes []*HierarchyNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].DepartmentID < nodes[j].DepartmentID
+	})
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HierarchyChange 2時点間での部門の変化
+type HierarchyChange struct {
+	DepartmentID            string  `json:"department_id"`
+	DepartmentName          string  `json:"department_name"`
+	OldParentDepartmentID   *string `json:"old_parent_department_id"`
+	OldParentDepartmentName *string `json:"old_parent_department_name"`
+	NewParentDepartmentID   *string `json:"new_parent_department_id"`
+	NewParentDepartmentName *string `json:"new_parent_department_name"`
+}
+
+// HierarchyDiff 2時点間の組織階層の差分
+type HierarchyDiff struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Added   []HierarchyChange `json:"added"`
+	Removed []HierarchyChange `json:"removed"`
+	Moved   []HierarchyChange `json:"moved"`
+}
+
+// DiffHierarchies 2時点の組織属性を比較し、新設・廃止・移管された部門を返す
+// 部門名は新設・移管では to 時点、廃止と移管前の上位部門では from 時点の名称を使う
+func DiffHierarchies(from, to time.Time, fromAttrs, toAttrs []OrganizationAttribute) *HierarchyDiff {
+	diff := &HierarchyDiff{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Added:   []HierarchyChange{},
+		Removed: []HierarchyChange{},
+		Moved:   []HierarchyChange{},
+	}
+
+	before := make(map[string]OrganizationAttribute, len(fromAttrs))
+	for _, a := range fromAttrs {
+		before[a.DepartmentID] = a
+	}
+	after := make(map[string]OrganizationAttribute, len(toAttrs))
+	for _, a := range toAttrs {
+		after[a.DepartmentID] = a
+	}
+
+	for _, a := range toAttrs {
+		b, existed := before[a.DepartmentID]
+		change := HierarchyChange{
+			DepartmentID:            a.DepartmentID,
+			DepartmentName:          a.Name(),
+			NewParentDepartmentID:   a.ParentDepartmentID,
+			NewParentDepartmentName: a.ParentName(),
+		}
+		if !existed {
+			diff.Added = append(diff.Added, change)
+			continue
+		}
+		if !sameParent(b.ParentDepartmentID, a.ParentDepartmentID) {
+			change.OldParentDepartmentID = b.ParentDepartmentID
+			change.OldParentDepartmentName = b.ParentName()
+			diff.Moved = append(diff.Moved, change)
+		}
+	}
+
+	for _, b := range fromAttrs {
+		if _, exists := after[b.DepartmentID]; exists {
+			continue
+		}
+		diff.Removed = append(diff.Removed, HierarchyChange{
+			DepartmentID:            b.DepartmentID,
+			DepartmentName:          b.Name(),
+			OldParentDepartmentID:   b.ParentDepartmentID,
+			OldParentDepartmentName: b.ParentName(),
+		})
+	}
+
+	for _, changes := range [][]HierarchyChange{diff.Added, diff.Removed, diff.Moved} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].DepartmentID < changes[j].DepartmentID
+		})
+	}
+
+	return diff
+}
+
+// Announcement 差分を組織改編の通知文として返す
+func (d *HierarchyDiff) Announcement() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "組織改編のお知らせ（%s → %s）\n", d.From, d.To)
+
+	label := func(name string, id string) string {
+		return fmt.Sprintf("%s（%s）", name, id)
+	}
+	parentLabel := func(id, name *string) string {
+		if id == nil {
+			return "最上位"
+		}
+		return label(*name, *id)
+	}
+
+	if len(d.Added) > 0 {
+		b.WriteString("\n■ 新設\n")
+		for _, c := range d.Added {
+			fmt.Fprintf(&b, "・%sを%sの配下に新設\n", label(c.DepartmentName, c.DepartmentID),
+				parentLabel(c.NewParentDepartmentID, c.NewParentDepartmentName))
+		}
+	}
+	if len(d.Moved) > 0 {
+		b.WriteString("\n■ 移管\n")
+		for _, c := range d.Moved {
+			fmt.Fprintf(&b, "・%sを%sから%sへ移管\n", label(c.DepartmentName, c.DepartmentID),
+				parentLabel(c.OldParentDepartmentID, c.OldParentDepartmentName),
+				parentLabel(c.NewParentDepartmentID, c.NewParentDepartmentName))
+		}
+	}
+	if len(d.Removed) > 0 {
+		b.WriteString("\n■ 廃止\n")
+		for _, c := range d.Removed {
+			fmt.Fprintf(&b, "・%s（%s配下）を廃止\n", label(c.DepartmentName, c.DepartmentID),
+				parentLabel(c.OldParentDepartmentID, c.OldParentDepartmentName))
+		}
+	}
+	if len(d.Added)+len(d.Moved)+len(d.Removed) == 0 {
+		b.WriteString("\n変更はありません\n")
+	}
+
+	return b.String()
+}
+
+func sameParent(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
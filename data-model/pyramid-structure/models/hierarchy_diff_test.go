@@ -0,0 +1,117 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffHierarchies(t *testing.T) {
+	from := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	str := func(s string) *string { return &s }
+	attr := func(id, name string, parentID, parentName *string) OrganizationAttribute {
+		return OrganizationAttribute{DepartmentID: id, DepartmentName: str(name), ParentDepartmentID: parentID, ParentDepartmentName: parentName}
+	}
+
+	tests := []struct {
+		name    string
+		before  []OrganizationAttribute
+		after   []OrganizationAttribute
+		added   []HierarchyChange
+		removed []HierarchyChange
+		moved   []HierarchyChange
+	}{
+		{
+			name:   "no changes",
+			before: []OrganizationAttribute{attr("HQ", "本社", nil, nil), attr("SALES", "営業部", str("HQ"), str("本社"))},
+			after:  []OrganizationAttribute{attr("HQ", "本社", nil, nil), attr("SALES", "営業本部", str("HQ"), str("本社"))},
+		},
+		{
+			name:   "added departments are sorted",
+			before: []OrganizationAttribute{attr("HQ", "本社", nil, nil)},
+			after: []OrganizationAttribute{
+				attr("HQ", "本社", nil, nil),
+				attr("SALES", "営業部", str("HQ"), str("本社")),
+				attr("DIGITAL", "デジタル戦略部", str("HQ"), str("本社")),
+			},
+			added: []HierarchyChange{
+				{DepartmentID: "DIGITAL", DepartmentName: "デジタル戦略部", NewParentDepartmentID: str("HQ"), NewParentDepartmentName: str("本社")},
+				{DepartmentID: "SALES", DepartmentName: "営業部", NewParentDepartmentID: str("HQ"), NewParentDepartmentName: str("本社")},
+			},
+		},
+		{
+			name:    "removed department uses the from names",
+			before:  []OrganizationAttribute{attr("HQ", "本社", nil, nil), attr("MFG", "製造本部", str("HQ"), str("本社"))},
+			after:   []OrganizationAttribute{attr("HQ", "本社", nil, nil)},
+			removed: []HierarchyChange{{DepartmentID: "MFG", DepartmentName: "製造本部", OldParentDepartmentID: str("HQ"), OldParentDepartmentName: str("本社")}},
+		},
+		{
+			name: "moved department",
+			before: []OrganizationAttribute{
+				attr("GENERAL", "総務部", str("HQ"), str("本社")),
+				attr("IT", "IT推進部", str("GENERAL"), str("総務部")),
+			},
+			after: []OrganizationAttribute{
+				attr("GENERAL", "総務部", str("HQ"), str("本社")),
+				attr("IT", "IT推進部", str("TECH"), str("技術本部")),
+			},
+			moved: []HierarchyChange{{
+				DepartmentID:            "IT",
+				DepartmentName:          "IT推進部",
+				OldParentDepartmentID:   str("GENERAL"),
+				OldParentDepartmentName: str("総務部"),
+				NewParentDepartmentID:   str("TECH"),
+				NewParentDepartmentName: str("技術本部"),
+			}},
+		},
+		{
+			name:   "moved to and from the top level",
+			before: []OrganizationAttribute{attr("SUPPORT", "営業支援部", str("SALES"), str("営業本部")), attr("HQ", "本社", nil, nil)},
+			after:  []OrganizationAttribute{attr("SUPPORT", "営業支援部", nil, nil), attr("HQ", "本社", str("GROUP"), str("グループ"))},
+			moved: []HierarchyChange{
+				{DepartmentID: "HQ", DepartmentName: "本社", NewParentDepartmentID: str("GROUP"), NewParentDepartmentName: str("グループ")},
+				{DepartmentID: "SUPPORT", DepartmentName: "営業支援部", OldParentDepartmentID: str("SALES"), OldParentDepartmentName: str("営業本部")},
+			},
+		},
+		{
+			name:   "names fall back to department ids",
+			before: []OrganizationAttribute{{DepartmentID: "A", ParentDepartmentID: str("X")}},
+			after:  []OrganizationAttribute{{DepartmentID: "A", ParentDepartmentID: str("Y")}},
+			moved: []HierarchyChange{{
+				DepartmentID:            "A",
+				DepartmentName:          "A",
+				OldParentDepartmentID:   str("X"),
+				OldParentDepartmentName: str("X"),
+				NewParentDepartmentID:   str("Y"),
+				NewParentDepartmentName: str("Y"),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffHierarchies(from, to, tt.before, tt.after)
+
+			if diff.From != "2024-03-31" || diff.To != "2024-04-01" {
+				t.Errorf("from/to = %s/%s, want 2024-03-31/2024-04-01", diff.From, diff.To)
+			}
+			for _, c := range []struct {
+				kind      string
+				got, want []HierarchyChange
+			}{
+				{"added", diff.Added, tt.added},
+				{"removed", diff.Removed, tt.removed},
+				{"moved", diff.Moved, tt.moved},
+			} {
+				want := c.want
+				if want == nil {
+					want = []HierarchyChange{}
+				}
+				if !reflect.DeepEqual(c.got, want) {
+					t.Errorf("%s = %+v, want %+v", c.kind, c.got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,819 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 組織構造の取り込みと出力
+// CSV による部門・組織属性の取り込みと、期間別の組織構造全体のスナップショット（JSON）の出力・取り込みを扱う
+// 取り込みはすべて1トランザクションで行い、問題が1件でもあれば何も変更しない
+
+// ImportIssue 取り込みの検証で見つかった問題
+type ImportIssue struct {
+	Line         int    `json:"line,omitempty"` // CSV の行番号（ヘッダーが1行目。スナップショットでは省略）
+	DepartmentID string `json:"department_id,omitempty"`
+	Message      string `json:"message"`
+}
+
+// ImportResult 取り込みの結果
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Valid   bool          `json:"valid"`
+	Created int           `json:"created"` // 新たに登録したレコード数
+	Updated int           `json:"updated"` // 既存のレコードを置き換えた件数
+	Deleted int           `json:"deleted"` // スナップショットの取り込みで削除したレコード数
+	Issues  []ImportIssue `json:"issues"`
+}
+
+// ImportValidationError 取り込みの適用時に検証エラーがあった場合のエラー
+type ImportValidationError struct {
+	Issues []ImportIssue
+}
+
+func (e *ImportValidationError) Error() string {
+	return fmt.Sprintf("Import has %d validation issues", len(e.Issues))
+}
+
+// SnapshotVersion スナップショットの形式のバージョン
+const SnapshotVersion = 1
+
+// Snapshot 期間別の組織構造全体（部門、部門名称、組織属性）
+type Snapshot struct {
+	Version     int                  `json:"version"`
+	ExportedAt  time.Time            `json:"exported_at"`
+	Departments []SnapshotDepartment `json:"departments"`
+}
+
+// SnapshotDepartment スナップショットの部門と、その部門名称・組織属性の履歴
+type SnapshotDepartment struct {
+	DepartmentID string              `json:"department_id"`
+	Names        []SnapshotName      `json:"names"`
+	Attributes   []SnapshotAttribute `json:"attributes"`
+}
+
+// SnapshotName スナップショットの部門名称のレコード
+type SnapshotName struct {
+	EffectiveDate  string  `json:"effective_date"`
+	DepartmentName string  `json:"department_name"`
+	ShortName      *string `json:"short_name"`
+}
+
+// SnapshotAttribute スナップショットの組織属性のレコード
+type SnapshotAttribute struct {
+	EffectiveDate      string  `json:"effective_date"`
+	ParentDepartmentID *string `json:"parent_department_id"`
+	Abolished          bool    `json:"abolished"`
+}
+
+type ImportRepository struct {
+	db *sql.DB
+}
+
+func NewImportRepository(db *sql.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+// runImport 取り込みを1トランザクションで実行する
+// 試行（dryRun）の場合と問題が見つかった場合はロールバックする。適用時に問題があれば ImportValidationError を返す
+func runImport(db *sql.DB, dryRun bool, fn func(tx *sql.Tx, result *ImportResult) error) (*ImportResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`LOCK TABLE organization_attributes IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{DryRun: dryRun, Issues: []ImportIssue{}}
+	if err := fn(tx, result); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result.Issues, func(a, b int) bool {
+		return result.Issues[a].Line < result.Issues[b].Line
+	})
+	result.Valid = len(result.Issues) == 0
+
+	if dryRun {
+		return result, nil
+	}
+	if !result.Valid {
+		return nil, &ImportValidationError{Issues: result.Issues}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// csvRow CSV のデータ行（列名で値を引く）
+type csvRow struct {
+	line   int
+	values map[string]string
+}
+
+// readCSV ヘッダー付きの CSV を読む
+// 列の順序は問わない。required の列は必須、optional の列は省略できる。それ以外の列があれば問題として返す
+func readCSV(r io.Reader, required, optional []string) ([]csvRow, []ImportIssue) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, []ImportIssue{{Line: 1, Message: "CSV is empty"}}
+	}
+	if err != nil {
+		return nil, []ImportIssue{{Line: 1, Message: err.Error()}}
+	}
+
+	known := map[string]bool{}
+	for _, name := range append(append([]string{}, required...), optional...) {
+		known[name] = true
+	}
+
+	var issues []ImportIssue
+	columns := map[string]int{}
+	for i, name := range header {
+		// Excel で保存した UTF-8 の CSV は先頭に BOM が付く
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			issues = append(issues, ImportIssue{Line: 1, Message: fmt.Sprintf("Unknown column %q", name)})
+			continue
+		}
+		if _, dup := columns[name]; dup {
+			issues = append(issues, ImportIssue{Line: 1, Message: fmt.Sprintf("Column %q appears more than once", name)})
+			continue
+		}
+		columns[name] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			issues = append(issues, ImportIssue{Line: 1, Message: fmt.Sprintf("Required column %q is missing", name)})
+		}
+	}
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	var rows []csvRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			}
+			return nil, []ImportIssue{{Line: line, Message: err.Error()}}
+		}
+		line, _ := reader.FieldPos(0)
+
+		// 空行は読み飛ばす
+		blank := true
+		for _, v := range record {
+			if strings.TrimSpace(v) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+
+		values := map[string]string{}
+		for name, i := range columns {
+			if i < len(record) {
+				values[name] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, csvRow{line: line, values: values})
+	}
+
+	if len(rows) == 0 {
+		return nil, []ImportIssue{{Line: 1, Message: "CSV has no data rows"}}
+	}
+	return rows, nil
+}
+
+// parseImportDate 取り込む発効年月日を解釈する
+func parseImportDate(value string) (time.Time, string) {
+	if value == "" {
+		return time.Time{}, "Effective date is required"
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Sprintf("Invalid effective date %q. Use YYYY-MM-DD", value)
+	}
+	return date, ""
+}
+
+// parseAbolished 廃止フラグを解釈する（空欄は false）
+func parseAbolished(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no":
+		return false, true
+	case "true", "1", "yes":
+		return true, true
+	}
+	return false, false
+}
+
+// recordKey 部門IDと発効年月日によるレコードのキー
+func recordKey(departmentID string, date time.Time) string {
+	return departmentID + "\x00" + date.Format("2006-01-02")
+}
+
+// upsertName 部門名称のレコードを登録または置き換え、新規に登録したかどうかを返す
+func upsertName(tx *sql.Tx, departmentID string, date time.Time, name string, shortName *string) (bool, error) {
+	var inserted bool
+	err := tx.QueryRow(`INSERT INTO department_names (department_id, effective_date, department_name, short_name)
+						VALUES ($1, $2, $3, $4)
+						ON CONFLICT (department_id, effective_date)
+						DO UPDATE SET department_name = EXCLUDED.department_name,
+									  short_name = EXCLUDED.short_name,
+									  updated_at = CURRENT_TIMESTAMP
+						RETURNING xmax = 0`,
+		departmentID, date, name, shortName).Scan(&inserted)
+	return inserted, err
+}
+
+// upsertAttribute 組織属性のレコードを登録または置き換え、新規に登録したかどうかを返す
+func upsertAttribute(tx *sql.Tx, departmentID string, date time.Time, parentID *string, abolished bool) (bool, error) {
+	var inserted bool
+	err := tx.QueryRow(`INSERT INTO organization_attributes (department_id, effective_date, parent_department_id, is_abolished)
+						VALUES ($1, $2, $3, $4)
+						ON CONFLICT (department_id, effective_date)
+						DO UPDATE SET parent_department_id = EXCLUDED.parent_department_id,
+									  is_abolished = EXCLUDED.is_abolished,
+									  updated_at = CURRENT_TIMESTAMP
+						RETURNING xmax = 0`,
+		departmentID, date, parentID, abolished).Scan(&inserted)
+	return inserted, err
+}
+
+// ImportDepartmentsCSV 部門と期間別の部門名称を CSV から取り込む
+// 列は department_id, effective_date, department_name, short_name（省略可）。未登録の部門は新設する
+// 同じ部門・発効年月日の部門名称が既にある場合は CSV の内容で置き換える
+func (r *ImportRepository) ImportDepartmentsCSV(csvData io.Reader, dryRun bool) (*ImportResult, error) {
+	return runImport(r.db, dryRun, func(tx *sql.Tx, result *ImportResult) error {
+		rows, issues := readCSV(csvData,
+			[]string{"department_id", "effective_date", "department_name"}, []string{"short_name"})
+		if len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			return nil
+		}
+
+		seen := map[string]int{}
+		for _, row := range rows {
+			departmentID := row.values["department_id"]
+			issue := func(message string) {
+				result.Issues = append(result.Issues, ImportIssue{Line: row.line, DepartmentID: departmentID, Message: message})
+			}
+
+			if departmentID == "" {
+				issue("Department ID is required")
+				continue
+			}
+			date, message := parseImportDate(row.values["effective_date"])
+			if message != "" {
+				issue(message)
+				continue
+			}
+			name := row.values["department_name"]
+			if name == "" {
+				issue("Department name is required")
+				continue
+			}
+			var shortName *string
+			if s := row.values["short_name"]; s != "" {
+				shortName = &s
+			}
+
+			key := recordKey(departmentID, date)
+			if line, dup := seen[key]; dup {
+				issue(fmt.Sprintf("Duplicate of line %d", line))
+				continue
+			}
+			seen[key] = row.line
+
+			created, err := tx.Exec(`INSERT INTO departments (department_id) VALUES ($1) ON CONFLICT DO NOTHING`, departmentID)
+			if err != nil {
+				return err
+			}
+			if n, err := created.RowsAffected(); err != nil {
+				return err
+			} else if n > 0 {
+				result.Created++
+			}
+
+			inserted, err := upsertName(tx, departmentID, date, name, shortName)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+
+		return nil
+	})
+}
+
+// ImportAttributesCSV 期間別の組織属性を CSV から取り込む
+// 列は department_id, effective_date, parent_department_id（省略可）, abolished（省略可）
+// 部門と上位部門は登録済みである必要がある。取り込んだすべてのレコードについて循環と期間の整合性を検証する
+func (r *ImportRepository) ImportAttributesCSV(csvData io.Reader, dryRun bool) (*ImportResult, error) {
+	return runImport(r.db, dryRun, func(tx *sql.Tx, result *ImportResult) error {
+		rows, issues := readCSV(csvData,
+			[]string{"department_id", "effective_date"}, []string{"parent_department_id", "abolished"})
+		if len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			return nil
+		}
+
+		type appliedRow struct {
+			line         int
+			departmentID string
+			date         time.Time
+		}
+		var applied []appliedRow
+		seen := map[string]int{}
+		for _, row := range rows {
+			departmentID := row.values["department_id"]
+			issue := func(message string) {
+				result.Issues = append(result.Issues, ImportIssue{Line: row.line, DepartmentID: departmentID, Message: message})
+			}
+
+			if departmentID == "" {
+				issue("Department ID is required")
+				continue
+			}
+			date, message := parseImportDate(row.values["effective_date"])
+			if message != "" {
+				issue(message)
+				continue
+			}
+			abolished, ok := parseAbolished(row.values["abolished"])
+			if !ok {
+				issue(fmt.Sprintf("Invalid abolished value %q. Use true or false", row.values["abolished"]))
+				continue
+			}
+			var parentID *string
+			if p := row.values["parent_department_id"]; p != "" {
+				parentID = &p
+			}
+			if abolished && parentID != nil {
+				issue("Abolition records cannot have a parent department")
+				continue
+			}
+
+			key := recordKey(departmentID, date)
+			if line, dup := seen[key]; dup {
+				issue(fmt.Sprintf("Duplicate of line %d", line))
+				continue
+			}
+			seen[key] = row.line
+
+			exists, err := departmentExists(tx, departmentID)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				issue(fmt.Sprintf("Department %s does not exist", departmentID))
+				continue
+			}
+			if parentID != nil {
+				parentExists, err := departmentExists(tx, *parentID)
+				if err != nil {
+					return err
+				}
+				if !parentExists {
+					issue(fmt.Sprintf("Parent department %s does not exist", *parentID))
+					continue
+				}
+			}
+
+			inserted, err := upsertAttribute(tx, departmentID, date, parentID, abolished)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+			applied = append(applied, appliedRow{line: row.line, departmentID: departmentID, date: date})
+		}
+
+		// すべての行を反映した後に検証する（CSV 内の行の順序に依存しない）
+		for _, a := range applied {
+			err := validateDepartmentChange(tx, a.departmentID, a.date)
+			if IsValidationError(err) {
+				result.Issues = append(result.Issues, ImportIssue{Line: a.line, DepartmentID: a.departmentID, Message: err.Error()})
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(result.Issues) > 0 {
+			return nil
+		}
+
+		changed := make([]string, 0, len(applied))
+		for _, a := range applied {
+			changed = append(changed, a.departmentID)
+		}
+		return refreshClosure(tx, changed...)
+	})
+}
+
+// ExportSnapshot 期間別の組織構造全体をスナップショットとして出力する
+func (r *ImportRepository) ExportSnapshot() (*Snapshot, error) {
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snapshot := &Snapshot{Version: SnapshotVersion, ExportedAt: time.Now(), Departments: []SnapshotDepartment{}}
+	index := map[string]int{}
+
+	rows, err := tx.Query(`SELECT department_id FROM departments ORDER BY department_id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[id] = len(snapshot.Departments)
+		snapshot.Departments = append(snapshot.Departments, SnapshotDepartment{
+			DepartmentID: id,
+			Names:        []SnapshotName{},
+			Attributes:   []SnapshotAttribute{},
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`SELECT department_id, effective_date, department_name, short_name
+						  FROM department_names
+						  ORDER BY department_id, effective_date`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		var date time.Time
+		var n SnapshotName
+		if err := rows.Scan(&id, &date, &n.DepartmentName, &n.ShortName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		n.EffectiveDate = date.Format("2006-01-02")
+		d := &snapshot.Departments[index[id]]
+		d.Names = append(d.Names, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`SELECT department_id, effective_date, parent_department_id, is_abolished
+						  FROM organization_attributes
+						  ORDER BY department_id, effective_date`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		var date time.Time
+		var a SnapshotAttribute
+		if err := rows.Scan(&id, &date, &a.ParentDepartmentID, &a.Abolished); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.EffectiveDate = date.Format("2006-01-02")
+		d := &snapshot.Departments[index[id]]
+		d.Attributes = append(d.Attributes, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// validateSnapshotContent スナップショットの内容だけで判定できる問題を返す
+func validateSnapshotContent(s *Snapshot) []ImportIssue {
+	var issues []ImportIssue
+	if s.Version != SnapshotVersion {
+		return []ImportIssue{{Message: fmt.Sprintf("Unsupported snapshot version %d", s.Version)}}
+	}
+
+	ids := map[string]bool{}
+	for _, d := range s.Departments {
+		if d.DepartmentID == "" {
+			issues = append(issues, ImportIssue{Message: "Department ID is required"})
+			continue
+		}
+		if ids[d.DepartmentID] {
+			issues = append(issues, ImportIssue{DepartmentID: d.DepartmentID, Message: "Department appears more than once"})
+		}
+		ids[d.DepartmentID] = true
+	}
+
+	for _, d := range s.Departments {
+		issue := func(message string) {
+			issues = append(issues, ImportIssue{DepartmentID: d.DepartmentID, Message: message})
+		}
+
+		if len(d.Names) == 0 {
+			issue("Department must have at least one name record")
+		}
+		dates := map[string]bool{}
+		for _, n := range d.Names {
+			if _, message := parseImportDate(n.EffectiveDate); message != "" {
+				issue("Name record: " + message)
+				continue
+			}
+			if n.DepartmentName == "" {
+				issue(fmt.Sprintf("Name record on %s: Department name is required", n.EffectiveDate))
+			}
+			if dates[n.EffectiveDate] {
+				issue(fmt.Sprintf("Name record on %s appears more than once", n.EffectiveDate))
+			}
+			dates[n.EffectiveDate] = true
+		}
+
+		dates = map[string]bool{}
+		for _, a := range d.Attributes {
+			if _, message := parseImportDate(a.EffectiveDate); message != "" {
+				issue("Attribute record: " + message)
+				continue
+			}
+			if a.Abolished && a.ParentDepartmentID != nil {
+				issue(fmt.Sprintf("Attribute record on %s: Abolition records cannot have a parent department", a.EffectiveDate))
+			}
+			if a.ParentDepartmentID != nil && !ids[*a.ParentDepartmentID] {
+				issue(fmt.Sprintf("Attribute record on %s: Parent department %s is not in the snapshot", a.EffectiveDate, *a.ParentDepartmentID))
+			}
+			if dates[a.EffectiveDate] {
+				issue(fmt.Sprintf("Attribute record on %s appears more than once", a.EffectiveDate))
+			}
+			dates[a.EffectiveDate] = true
+		}
+	}
+
+	return issues
+}
+
+// ImportSnapshot スナップショットで期間別の組織構造全体を置き換える
+// スナップショットにない部門は削除する（社員の所属・部門長で参照されている部門がある場合は問題として返す）
+// 部門の登録日時などは取り込み時のものになり、組織属性の置き換えは記録時点の履歴に残る
+func (r *ImportRepository) ImportSnapshot(s *Snapshot, dryRun bool) (*ImportResult, error) {
+	return runImport(r.db, dryRun, func(tx *sql.Tx, result *ImportResult) error {
+		if issues := validateSnapshotContent(s); len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			return nil
+		}
+
+		ids := make([]string, len(s.Departments))
+		for i, d := range s.Departments {
+			ids[i] = d.DepartmentID
+		}
+
+		// 社員の所属・部門長は部門の削除で連鎖して消えないよう、参照されている部門の削除を拒否する
+		for _, ref := range []struct{ table, description string }{
+			{"employee_assignments", "employee assignments"},
+			{"department_heads", "department heads"},
+		} {
+			rows, err := tx.Query(`SELECT DISTINCT department_id
+								   FROM `+ref.table+`
+								   WHERE NOT department_id = ANY($1)
+								   ORDER BY department_id`, pq.Array(ids))
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var id string
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return err
+				}
+				result.Issues = append(result.Issues, ImportIssue{DepartmentID: id,
+					Message: fmt.Sprintf("Department %s is not in the snapshot but has %s", id, ref.description)})
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+		if len(result.Issues) > 0 {
+			return nil
+		}
+
+		// 組織属性と部門名称を一旦すべて削除してから、スナップショットの内容で登録し直す
+		for _, query := range []string{
+			`DELETE FROM organization_attributes`,
+			`DELETE FROM department_names`,
+		} {
+			deleted, err := tx.Exec(query)
+			if err != nil {
+				return err
+			}
+			n, err := deleted.RowsAffected()
+			if err != nil {
+				return err
+			}
+			result.Deleted += int(n)
+		}
+		deleted, err := tx.Exec(`DELETE FROM departments WHERE NOT department_id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		n, err := deleted.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result.Deleted += int(n)
+
+		for _, d := range s.Departments {
+			if _, err := tx.Exec(`INSERT INTO departments (department_id) VALUES ($1) ON CONFLICT DO NOTHING`, d.DepartmentID); err != nil {
+				return err
+			}
+			for _, name := range d.Names {
+				date, _ := time.Parse("2006-01-02", name.EffectiveDate)
+				if _, err := upsertName(tx, d.DepartmentID, date, name.DepartmentName, name.ShortName); err != nil {
+					return err
+				}
+				result.Created++
+			}
+		}
+		// 上位部門の参照先はすべて登録済みなので、部門の順序によらず登録できる
+		for _, d := range s.Departments {
+			for _, a := range d.Attributes {
+				date, _ := time.Parse("2006-01-02", a.EffectiveDate)
+				if _, err := upsertAttribute(tx, d.DepartmentID, date, a.ParentDepartmentID, a.Abolished); err != nil {
+					return err
+				}
+				result.Created++
+			}
+		}
+
+		issues, err := validateAllDepartments(tx)
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			return nil
+		}
+
+		_, err = tx.Exec(`SELECT refresh_organization_closure(NULL)`)
+		return err
+	})
+}
+
+// validateAllDepartments すべての発効日について、組織構造全体の循環と上位部門の存在を検証する
+// 各発効日で最初に見つかった問題を返す
+func validateAllDepartments(tx *sql.Tx) ([]ImportIssue, error) {
+	rows, err := tx.Query(`SELECT DISTINCT effective_date FROM organization_attributes ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	var dates []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var issues []ImportIssue
+	for _, date := range dates {
+		err := checkAllCycles(tx, date)
+		if err == nil {
+			err = checkAllParentsActive(tx, date)
+		}
+		if IsValidationError(err) {
+			issues = append(issues, ImportIssue{DepartmentID: validationDepartmentID(err), Message: err.Error()})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// スナップショットに含まれない社員の所属・部門長が、取り込んだ組織で部門が存在しない期間にかからないこと
+	staffed, err := checkStaffedDepartments(tx, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range staffed {
+		issues = append(issues, ImportIssue{DepartmentID: e.DepartmentID, Message: e.Error()})
+	}
+
+	return issues, nil
+}
+
+// validationDepartmentID 検証エラーの対象の部門ID
+func validationDepartmentID(err error) string {
+	var cycleErr *CycleError
+	var parentErr *ParentNotActiveError
+	switch {
+	case errors.As(err, &cycleErr):
+		return cycleErr.Path[0]
+	case errors.As(err, &parentErr):
+		return parentErr.DepartmentID
+	}
+	return ""
+}
+
+// checkAllCycles 指定日付時点の組織構造全体に循環があれば CycleError を返す
+func checkAllCycles(tx *sql.Tx, date time.Time) error {
+	query := `
+		WITH RECURSIVE as_of AS (` + asOfAttributesAt("$1") + `),
+		walk AS (
+			SELECT a.department_id::text AS start_id, a.parent_department_id::text AS department_id,
+				   ARRAY[a.department_id::text] AS path
+			FROM as_of a
+			WHERE a.parent_department_id IS NOT NULL
+			UNION ALL
+			SELECT w.start_id, a.parent_department_id::text, w.path || w.department_id
+			FROM walk w
+			JOIN as_of a ON a.department_id = w.department_id
+			WHERE a.parent_department_id IS NOT NULL AND NOT w.department_id = ANY(w.path)
+		)
+		SELECT path || department_id
+		FROM walk
+		WHERE department_id = start_id
+		ORDER BY start_id
+		LIMIT 1`
+
+	var path []string
+	err := tx.QueryRow(query, date).Scan(pq.Array(&path))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &CycleError{Date: date, Path: path}
+}
+
+// checkAllParentsActive 指定日付時点で、上位部門が組織に存在しない存続部門があれば ParentNotActiveError を返す
+func checkAllParentsActive(tx *sql.Tx, date time.Time) error {
+	query := `
+		WITH as_of AS (` + asOfAttributesAt("$1") + `)
+		SELECT child.department_id, child.parent_department_id
+		FROM as_of child
+		LEFT JOIN as_of parent ON parent.department_id = child.parent_department_id
+		WHERE NOT child.is_abolished
+		AND child.parent_department_id IS NOT NULL
+		AND (parent.department_id IS NULL OR parent.is_abolished)
+		ORDER BY child.department_id
+		LIMIT 1`
+
+	var departmentID, parentID string
+	err := tx.QueryRow(query, date).Scan(&departmentID, &parentID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &ParentNotActiveError{DepartmentID: departmentID, ParentDepartmentID: parentID, Date: date}
+}
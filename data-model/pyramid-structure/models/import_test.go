@@ -0,0 +1,210 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	required := []string{"department_id", "effective_date"}
+	optional := []string{"short_name"}
+
+	tests := []struct {
+		name   string
+		input  string
+		rows   []csvRow
+		issues []ImportIssue
+	}{
+		{
+			name:  "columns in any order",
+			input: "effective_date,department_id\n2024-04-01,HQ\n2024-04-01,SALES\n",
+			rows: []csvRow{
+				{line: 2, values: map[string]string{"department_id": "HQ", "effective_date": "2024-04-01"}},
+				{line: 3, values: map[string]string{"department_id": "SALES", "effective_date": "2024-04-01"}},
+			},
+		},
+		{
+			name:  "byte order mark and spaces",
+			input: "\ufeffdepartment_id, effective_date ,short_name\n HQ , 2024-04-01,本社\n",
+			rows: []csvRow{
+				{line: 2, values: map[string]string{"department_id": "HQ", "effective_date": "2024-04-01", "short_name": "本社"}},
+			},
+		},
+		{
+			name:  "blank lines are skipped",
+			input: "department_id,effective_date\n\nHQ,2024-04-01\n,\nSALES,2024-04-01\n",
+			rows: []csvRow{
+				{line: 3, values: map[string]string{"department_id": "HQ", "effective_date": "2024-04-01"}},
+				{line: 5, values: map[string]string{"department_id": "SALES", "effective_date": "2024-04-01"}},
+			},
+		},
+		{
+			name:  "short rows leave optional columns out",
+			input: "department_id,effective_date,short_name\nHQ,2024-04-01\n",
+			rows: []csvRow{
+				{line: 2, values: map[string]string{"department_id": "HQ", "effective_date": "2024-04-01"}},
+			},
+		},
+		{
+			name:   "empty",
+			input:  "",
+			issues: []ImportIssue{{Line: 1, Message: "CSV is empty"}},
+		},
+		{
+			name:   "header only",
+			input:  "department_id,effective_date\n",
+			issues: []ImportIssue{{Line: 1, Message: "CSV has no data rows"}},
+		},
+		{
+			name:  "unknown, duplicate and missing columns",
+			input: "department_id,department_id,parent\nHQ,HQ,\n",
+			issues: []ImportIssue{
+				{Line: 1, Message: `Column "department_id" appears more than once`},
+				{Line: 1, Message: `Unknown column "parent"`},
+				{Line: 1, Message: `Required column "effective_date" is missing`},
+			},
+		},
+		{
+			name:   "parse error reports the line",
+			input:  "department_id,effective_date\nHQ,2024-04-01\n\"SALES,2024-04-01\n",
+			issues: []ImportIssue{{Line: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, issues := readCSV(strings.NewReader(tt.input), required, optional)
+
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("rows = %+v, want %+v", rows, tt.rows)
+			}
+			if len(issues) != len(tt.issues) {
+				t.Fatalf("issues = %+v, want %+v", issues, tt.issues)
+			}
+			for i, want := range tt.issues {
+				got := issues[i]
+				// 構文エラーのメッセージは encoding/csv のものなので行番号だけを比べる
+				if got.Line != want.Line || (want.Message != "" && got.Message != want.Message) {
+					t.Errorf("issues[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAbolished(t *testing.T) {
+	tests := []struct {
+		value     string
+		abolished bool
+		ok        bool
+	}{
+		{"", false, true},
+		{"false", false, true},
+		{"FALSE", false, true},
+		{"0", false, true},
+		{"no", false, true},
+		{"true", true, true},
+		{"True", true, true},
+		{"1", true, true},
+		{"yes", true, true},
+		{"YES", true, true},
+		{"2", false, false},
+		{"abolished", false, false},
+		{"廃止", false, false},
+	}
+
+	for _, tt := range tests {
+		abolished, ok := parseAbolished(tt.value)
+		if abolished != tt.abolished || ok != tt.ok {
+			t.Errorf("parseAbolished(%q) = (%v, %v), want (%v, %v)", tt.value, abolished, ok, tt.abolished, tt.ok)
+		}
+	}
+}
+
+func TestValidateSnapshotContent(t *testing.T) {
+	parent := func(id string) *string { return &id }
+	name := func(date, value string) SnapshotName {
+		return SnapshotName{EffectiveDate: date, DepartmentName: value}
+	}
+	department := func(id string, attributes ...SnapshotAttribute) SnapshotDepartment {
+		return SnapshotDepartment{DepartmentID: id, Names: []SnapshotName{name("2023-01-01", "部門")}, Attributes: attributes}
+	}
+
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		issues   []ImportIssue
+	}{
+		{
+			name: "valid",
+			snapshot: Snapshot{Version: SnapshotVersion, Departments: []SnapshotDepartment{
+				department("HQ", SnapshotAttribute{EffectiveDate: "2023-01-01"}),
+				department("SALES",
+					SnapshotAttribute{EffectiveDate: "2023-01-01", ParentDepartmentID: parent("HQ")},
+					SnapshotAttribute{EffectiveDate: "2024-04-01", Abolished: true}),
+			}},
+		},
+		{
+			name:     "unsupported version",
+			snapshot: Snapshot{Version: SnapshotVersion + 1, Departments: []SnapshotDepartment{{}}},
+			issues:   []ImportIssue{{Message: "Unsupported snapshot version 2"}},
+		},
+		{
+			name: "missing and duplicate department ids",
+			snapshot: Snapshot{Version: SnapshotVersion, Departments: []SnapshotDepartment{
+				department(""),
+				department("HQ"),
+				department("HQ"),
+			}},
+			issues: []ImportIssue{
+				{Message: "Department ID is required"},
+				{DepartmentID: "HQ", Message: "Department appears more than once"},
+			},
+		},
+		{
+			name: "name records",
+			snapshot: Snapshot{Version: SnapshotVersion, Departments: []SnapshotDepartment{
+				{DepartmentID: "HQ"},
+				{DepartmentID: "SALES", Names: []SnapshotName{
+					name("2023-01-01", "営業部"),
+					name("2023-01-01", "営業本部"),
+					name("2024/04/01", "営業本部"),
+					name("2024-04-01", ""),
+				}},
+			}},
+			issues: []ImportIssue{
+				{DepartmentID: "HQ", Message: "Department must have at least one name record"},
+				{DepartmentID: "SALES", Message: "Name record on 2023-01-01 appears more than once"},
+				{DepartmentID: "SALES", Message: `Name record: Invalid effective date "2024/04/01". Use YYYY-MM-DD`},
+				{DepartmentID: "SALES", Message: "Name record on 2024-04-01: Department name is required"},
+			},
+		},
+		{
+			name: "attribute records",
+			snapshot: Snapshot{Version: SnapshotVersion, Departments: []SnapshotDepartment{
+				department("HQ", SnapshotAttribute{EffectiveDate: "2023-01-01"}),
+				department("SALES",
+					SnapshotAttribute{EffectiveDate: ""},
+					SnapshotAttribute{EffectiveDate: "2023-01-01", ParentDepartmentID: parent("UNKNOWN")},
+					SnapshotAttribute{EffectiveDate: "2023-01-01", ParentDepartmentID: parent("HQ")},
+					SnapshotAttribute{EffectiveDate: "2024-04-01", ParentDepartmentID: parent("HQ"), Abolished: true}),
+			}},
+			issues: []ImportIssue{
+				{DepartmentID: "SALES", Message: "Attribute record: Effective date is required"},
+				{DepartmentID: "SALES", Message: "Attribute record on 2023-01-01: Parent department UNKNOWN is not in the snapshot"},
+				{DepartmentID: "SALES", Message: "Attribute record on 2023-01-01 appears more than once"},
+				{DepartmentID: "SALES", Message: "Attribute record on 2024-04-01: Abolition records cannot have a parent department"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validateSnapshotContent(&tt.snapshot)
+			if !reflect.DeepEqual(issues, tt.issues) {
+				t.Errorf("issues = %+v, want %+v", issues, tt.issues)
+			}
+		})
+	}
+}
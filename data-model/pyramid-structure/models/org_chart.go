@@ -0,0 +1,230 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 組織図の出力形式
+const (
+	ChartFormatDOT     = "dot"
+	ChartFormatMermaid = "mermaid"
+	ChartFormatSVG     = "svg"
+)
+
+// chartTitle 組織図の表題
+func chartTitle(date time.Time) string {
+	return "組織図（" + date.Format("2006-01-02") + "）"
+}
+
+// walkChart 組織図のノードを親から順にたどる
+func walkChart(nodes []*HierarchyNode, fn func(node *HierarchyNode)) {
+	for _, node := range nodes {
+		fn(node)
+		walkChart(node.Children, fn)
+	}
+}
+
+// RenderDOT 組織階層ツリーを Graphviz の DOT 形式で出力する
+func RenderDOT(tree []*HierarchyNode, date time.Time) string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph org_chart {\n")
+	fmt.Fprintf(&b, "    graph [rankdir=TB, label=%s, labelloc=t, fontname=\"sans-serif\"];\n", quote(chartTitle(date)))
+	b.WriteString("    node [shape=box, style=rounded, fontname=\"sans-serif\"];\n")
+	b.WriteString("    edge [arrowhead=none];\n\n")
+
+	walkChart(tree, func(node *HierarchyNode) {
+		fmt.Fprintf(&b, "    %s [label=%s];\n", quote(node.DepartmentID), quote(node.DepartmentName))
+	})
+	b.WriteString("\n")
+	walkChart(tree, func(node *HierarchyNode) {
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "    %s -> %s;\n", quote(node.DepartmentID), quote(child.DepartmentID))
+		}
+	})
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var mermaidIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// RenderMermaid 組織階層ツリーを Mermaid の flowchart 形式で出力する
+// Mermaid のノードIDに使えない部門IDは、他の部門IDと重ならない連番のIDに置き換える
+func RenderMermaid(tree []*HierarchyNode, date time.Time) string {
+	ids := map[string]string{}
+	used := map[string]bool{}
+	walkChart(tree, func(node *HierarchyNode) {
+		if mermaidIDPattern.MatchString(node.DepartmentID) {
+			ids[node.DepartmentID] = node.DepartmentID
+			used[node.DepartmentID] = true
+		}
+	})
+	seq := 0
+	walkChart(tree, func(node *HierarchyNode) {
+		if _, ok := ids[node.DepartmentID]; ok {
+			return
+		}
+		id := ""
+		for id == "" || used[id] {
+			seq++
+			id = fmt.Sprintf("dept_%d", seq)
+		}
+		ids[node.DepartmentID] = id
+		used[id] = true
+	})
+
+	label := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("title: " + chartTitle(date) + "\n")
+	b.WriteString("---\n")
+	b.WriteString("flowchart TD\n")
+	walkChart(tree, func(node *HierarchyNode) {
+		fmt.Fprintf(&b, "    %s[%s]\n", ids[node.DepartmentID], label(node.DepartmentName))
+	})
+	walkChart(tree, func(node *HierarchyNode) {
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "    %s --> %s\n", ids[node.DepartmentID], ids[child.DepartmentID])
+		}
+	})
+
+	return b.String()
+}
+
+// SVG の組織図のレイアウト（単位はピクセル）
+const (
+	svgFontSize    = 14
+	svgIDFontSize  = 10
+	svgBoxPadding  = 16
+	svgBoxMinWidth = 96
+	svgBoxHeight   = 48
+	svgColumnGap   = 16
+	svgLevelGap    = 40
+	svgMargin      = 24
+	svgTitleHeight = 32
+)
+
+// textWidth 文字列のおおよその表示幅（全角文字を1文字分、半角文字を0.6文字分とする）
+func textWidth(s string, fontSize int) int {
+	width := 0.0
+	for _, r := range s {
+		if utf8.RuneLen(r) == 1 {
+			width += 0.6
+		} else {
+			width += 1.0
+		}
+	}
+	return int(width*float64(fontSize) + 0.5)
+}
+
+// RenderSVG 組織階層ツリーを SVG 形式の組織図として出力する
+// 末端の部門を左から順に並べ、上位部門は配下の部門の中央に置く。外部のツールは使わない
+func RenderSVG(tree []*HierarchyNode, date time.Time) string {
+	title := chartTitle(date)
+
+	// 箱の幅はすべての部門で揃える
+	boxWidth := svgBoxMinWidth
+	maxDepth := -1
+	walkChart(tree, func(node *HierarchyNode) {
+		w := textWidth(node.DepartmentName, svgFontSize)
+		if idWidth := textWidth(node.DepartmentID, svgIDFontSize); idWidth > w {
+			w = idWidth
+		}
+		if w+2*svgBoxPadding > boxWidth {
+			boxWidth = w + 2*svgBoxPadding
+		}
+		if node.Depth-tree[0].Depth > maxDepth {
+			maxDepth = node.Depth - tree[0].Depth
+		}
+	})
+
+	// 各部門の箱の左端を求める
+	x := map[string]int{}
+	next := 0
+	var place func(node *HierarchyNode)
+	place = func(node *HierarchyNode) {
+		if len(node.Children) == 0 {
+			x[node.DepartmentID] = next * (boxWidth + svgColumnGap)
+			next++
+			return
+		}
+		for _, child := range node.Children {
+			place(child)
+		}
+		first := x[node.Children[0].DepartmentID]
+		last := x[node.Children[len(node.Children)-1].DepartmentID]
+		x[node.DepartmentID] = (first + last) / 2
+	}
+	for _, root := range tree {
+		place(root)
+	}
+
+	chartWidth := next*(boxWidth+svgColumnGap) - svgColumnGap
+	if titleWidth := textWidth(title, svgFontSize+2); titleWidth > chartWidth {
+		chartWidth = titleWidth
+	}
+	if chartWidth < 0 {
+		chartWidth = 0
+	}
+	width := chartWidth + 2*svgMargin
+	height := svgMargin + svgTitleHeight + (maxDepth+1)*(svgBoxHeight+svgLevelGap) - svgLevelGap + svgMargin
+	if maxDepth < 0 {
+		height = 2*svgMargin + svgTitleHeight
+	}
+
+	top := func(node *HierarchyNode) int {
+		return svgMargin + svgTitleHeight + (node.Depth-tree[0].Depth)*(svgBoxHeight+svgLevelGap)
+	}
+	left := func(node *HierarchyNode) int {
+		return svgMargin + x[node.DepartmentID]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `  <title>%s</title>`+"\n", html.EscapeString(title))
+	fmt.Fprintf(&b, `  <rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `  <text x="%d" y="%d" font-size="%d" font-weight="bold">%s</text>`+"\n",
+		svgMargin, svgMargin+svgFontSize, svgFontSize+2, html.EscapeString(title))
+
+	// 上位部門の箱の下端から、配下の部門の箱の上端へ直角に線を引く
+	b.WriteString(`  <g fill="none" stroke="#666666" stroke-width="1">` + "\n")
+	walkChart(tree, func(node *HierarchyNode) {
+		px := left(node) + boxWidth/2
+		py := top(node) + svgBoxHeight
+		for _, child := range node.Children {
+			cx := left(child) + boxWidth/2
+			fmt.Fprintf(&b, `    <path d="M%d %d V%d H%d V%d"/>`+"\n", px, py, py+svgLevelGap/2, cx, top(child))
+		}
+	})
+	b.WriteString("  </g>\n")
+
+	walkChart(tree, func(node *HierarchyNode) {
+		bx, by := left(node), top(node)
+		cx := bx + boxWidth/2
+		b.WriteString("  <g>\n")
+		fmt.Fprintf(&b, `    <title>%s</title>`+"\n", html.EscapeString(node.FullPath))
+		fmt.Fprintf(&b, `    <rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="#f5f8fc" stroke="#34495e"/>`+"\n",
+			bx, by, boxWidth, svgBoxHeight)
+		fmt.Fprintf(&b, `    <text x="%d" y="%d" font-size="%d" text-anchor="middle">%s</text>`+"\n",
+			cx, by+svgBoxHeight/2, svgFontSize, html.EscapeString(node.DepartmentName))
+		fmt.Fprintf(&b, `    <text x="%d" y="%d" font-size="%d" text-anchor="middle" fill="#7f8c8d">%s</text>`+"\n",
+			cx, by+svgBoxHeight/2+svgIDFontSize+4, svgIDFontSize, html.EscapeString(node.DepartmentID))
+		b.WriteString("  </g>\n")
+	})
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var chartDate = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+// chartNode テスト用の組織図ノード（配下の部門の深さと経路を設定する）
+func chartNode(id, name string, children ...*HierarchyNode) *HierarchyNode {
+	node := &HierarchyNode{DepartmentID: id, DepartmentName: name, Path: []string{id}, FullPath: name, Children: children}
+	var setDepth func(n *HierarchyNode)
+	setDepth = func(n *HierarchyNode) {
+		for _, c := range n.Children {
+			c.Depth = n.Depth + 1
+			c.Path = append(append([]string{}, n.Path...), c.Path[len(c.Path)-1])
+			c.FullPath = n.FullPath + fullPathSeparator + c.DepartmentName
+			setDepth(c)
+		}
+	}
+	setDepth(node)
+	return node
+}
+
+func sampleChart() []*HierarchyNode {
+	return []*HierarchyNode{
+		chartNode("HQ", "本社",
+			chartNode("SALES", "営業本部",
+				chartNode("SALES_1", "営業1部")),
+			chartNode("TECH_HQ", "技術本部")),
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     []*HierarchyNode
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "nodes and edges",
+			tree: sampleChart(),
+			contains: []string{
+				"digraph org_chart {\n",
+				`label="組織図（2024-04-01）"`,
+				`    "HQ" [label="本社"];`,
+				`    "SALES_1" [label="営業1部"];`,
+				`    "HQ" -> "SALES";`,
+				`    "HQ" -> "TECH_HQ";`,
+				`    "SALES" -> "SALES_1";`,
+			},
+			excludes: []string{`"TECH_HQ" -> `},
+		},
+		{
+			name: "escapes quotes and backslashes",
+			tree: []*HierarchyNode{chartNode(`A"1`, `名称\"引用"`)},
+			contains: []string{
+				`    "A\"1" [label="名称\\\"引用\""];`,
+			},
+		},
+		{
+			name:     "empty tree",
+			tree:     nil,
+			contains: []string{"digraph org_chart {\n", "}\n"},
+			excludes: []string{"->", "[label="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderDOT(tt.tree, chartDate)
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("output does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("output contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     []*HierarchyNode
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "nodes and edges",
+			tree: sampleChart(),
+			contains: []string{
+				"---\ntitle: 組織図（2024-04-01）\n---\nflowchart TD\n",
+				`    HQ["本社"]`,
+				`    SALES_1["営業1部"]`,
+				"    HQ --> SALES\n",
+				"    HQ --> TECH_HQ\n",
+				"    SALES --> SALES_1\n",
+			},
+		},
+		{
+			name: "invalid ids are replaced",
+			tree: []*HierarchyNode{chartNode("HQ", "本社", chartNode("1ST", "第一部"), chartNode("SALES-2", "第二部"))},
+			contains: []string{
+				`    dept_1["第一部"]`,
+				`    dept_2["第二部"]`,
+				"    HQ --> dept_1\n",
+				"    HQ --> dept_2\n",
+			},
+		},
+		{
+			name: "replacement does not collide with a department id",
+			tree: []*HierarchyNode{chartNode("dept_1", "本社", chartNode("1ST", "第一部"), chartNode("dept_2", "第二部"))},
+			contains: []string{
+				`    dept_1["本社"]`,
+				`    dept_2["第二部"]`,
+				`    dept_3["第一部"]`,
+				"    dept_1 --> dept_3\n",
+				"    dept_1 --> dept_2\n",
+			},
+		},
+		{
+			name:     "quotes in names",
+			tree:     []*HierarchyNode{chartNode("HQ", `"本社"`)},
+			contains: []string{`    HQ["#quot;本社#quot;"]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMermaid(tt.tree, chartDate)
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("output does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("output contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     []*HierarchyNode
+		boxes    int
+		paths    int
+		contains []string
+	}{
+		{
+			name:  "sample chart",
+			tree:  sampleChart(),
+			boxes: 4,
+			paths: 3,
+			contains: []string{
+				`<title>組織図（2024-04-01）</title>`,
+				`<title>本社 / 営業本部 / 営業1部</title>`,
+				`>営業1部</text>`,
+				`>SALES_1</text>`,
+			},
+		},
+		{
+			name:     "escapes names",
+			tree:     []*HierarchyNode{chartNode("R&D", "<研究>")},
+			boxes:    1,
+			paths:    0,
+			contains: []string{`>&lt;研究&gt;</text>`, `>R&amp;D</text>`},
+		},
+		{
+			name:  "empty tree",
+			tree:  nil,
+			boxes: 0,
+			paths: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderSVG(tt.tree, chartDate)
+
+			// 整形式の XML であること
+			decoder := xml.NewDecoder(strings.NewReader(got))
+			for {
+				_, err := decoder.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("invalid SVG: %v\n%s", err, got)
+				}
+			}
+
+			// 背景の rect を除いた部門の箱の数
+			if boxes := strings.Count(got, "<rect ") - 1; boxes != tt.boxes {
+				t.Errorf("boxes = %d, want %d", boxes, tt.boxes)
+			}
+			if paths := strings.Count(got, "<path "); paths != tt.paths {
+				t.Errorf("paths = %d, want %d", paths, tt.paths)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("output does not contain %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSVGCentersParents(t *testing.T) {
+	got := RenderSVG(sampleChart(), chartDate)
+
+	// 部門IDの text 要素の x 座標（箱の中央）
+	x := func(id string) int {
+		t.Helper()
+		i := strings.Index(got, ">"+id+"</text>")
+		if i < 0 {
+			t.Fatalf("department %s not found", id)
+		}
+		start := strings.LastIndex(got[:i], `<text x="`) + len(`<text x="`)
+		v, err := strconv.Atoi(got[start : start+strings.Index(got[start:], `"`)])
+		if err != nil {
+			t.Fatalf("x of %s: %v", id, err)
+		}
+		return v
+	}
+
+	// 末端の部門（営業1部、技術本部）は左から並び、営業本部は営業1部の真上、本社はその中央に置かれる
+	if x("SALES_1") >= x("TECH_HQ") {
+		t.Errorf("SALES_1 x = %d, want left of TECH_HQ x = %d", x("SALES_1"), x("TECH_HQ"))
+	}
+	if x("SALES") != x("SALES_1") {
+		t.Errorf("SALES x = %d, want %d", x("SALES"), x("SALES_1"))
+	}
+	if want := (x("SALES") + x("TECH_HQ")) / 2; x("HQ") != want {
+		t.Errorf("HQ x = %d, want %d", x("HQ"), want)
+	}
+}
@@ -9,11 +9,37 @@ type OrganizationAttribute struct {
 	DepartmentID       string     `json:"department_id"`
 	EffectiveDate      time.Time  `json:"effective_date"`
 	ParentDepartmentID *string    `json:"parent_department_id"`
+	Abolished          bool       `json:"abolished"` // 廃止レコード（発効日以降は組織に存在しない）
 	ExpirationDate     *time.Time `json:"expiration_date"` // 導出属性
+	// 部門名・上位部門名（導出属性）
+	// 階層の取得では基準日時点、それ以外ではレコードの発効日時点で有効な名称
+	DepartmentName       *string `json:"department_name,omitempty"`
+	ParentDepartmentName *string `json:"parent_department_name,omitempty"`
+	// 記録日時（導出属性、as_known_at を指定した取得のみ）
+	RecordedAt         *time.Time `json:"recorded_at,omitempty"`
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
+// Name 部門名を返す（名称が登録されていない場合は部門ID）
+func (a OrganizationAttribute) Name() string {
+	if a.DepartmentName != nil {
+		return *a.DepartmentName
+	}
+	return a.DepartmentID
+}
+
+// ParentName 上位部門名を返す（最上位の場合は nil）
+func (a OrganizationAttribute) ParentName() *string {
+	if a.ParentDepartmentID == nil {
+		return nil
+	}
+	if a.ParentDepartmentName != nil {
+		return a.ParentDepartmentName
+	}
+	return a.ParentDepartmentID
+}
+
 type OrganizationAttributeRepository struct {
 	db *sql.DB
 }
@@ -29,6 +55,7 @@ func (r *OrganizationAttributeRepository) GetAll() ([]OrganizationAttribute, err
 				oa1.department_id,
 				oa1.effective_date,
 				oa1.parent_department_id,
+				oa1.is_abolished,
 				oa1.created_at,
 				oa1.updated_at,
 				(
@@ -40,14 +67,19 @@ func (r *OrganizationAttributeRepository) GetAll() ([]OrganizationAttribute, err
 			FROM organization_attributes oa1
 		)
 		SELECT 
-			department_id,
-			effective_date,
-			parent_department_id,
-			expiration_date,
-			created_at,
-			updated_at
-		FROM org_attrs
-		ORDER BY department_id, effective_date DESC`
+			oa.department_id,
+			oa.effective_date,
+			oa.parent_department_id,
+			oa.is_abolished,
+			oa.expiration_date,
+			n.department_name,
+			pn.department_name,
+			oa.created_at,
+			oa.updated_at
+		FROM org_attrs oa
+		` + nameAtJoin("n", "oa.department_id", "oa.effective_date") + `
+		` + nameAtJoin("pn", "oa.parent_department_id", "oa.effective_date") + `
+		ORDER BY oa.department_id, oa.effective_date DESC`
 	
 	rows, err := r.db.Query(query)
 	if err != nil {
@@ -58,8 +90,8 @@ func (r *OrganizationAttributeRepository) GetAll() ([]OrganizationAttribute, err
 	var attrs []OrganizationAttribute
 	for rows.Next() {
 		var a OrganizationAttribute
-		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, 
-			&a.ExpirationDate, &a.CreatedAt, &a.UpdatedAt)
+		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, &a.Abolished,
+			&a.ExpirationDate, &a.DepartmentName, &a.ParentDepartmentName, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -75,21 +107,26 @@ func (r *OrganizationAttributeRepository) GetByID(departmentID string, effective
 			oa1.department_id,
 			oa1.effective_date,
 			oa1.parent_department_id,
+			oa1.is_abolished,
 			(
 				SELECT MIN(oa2.effective_date) - INTERVAL '1 day'
 				FROM organization_attributes oa2
 				WHERE oa2.department_id = oa1.department_id
 				AND oa2.effective_date > oa1.effective_date
 			) AS expiration_date,
+			n.department_name,
+			pn.department_name,
 			oa1.created_at,
 			oa1.updated_at
 		FROM organization_attributes oa1
+		` + nameAtJoin("n", "oa1.department_id", "oa1.effective_date") + `
+		` + nameAtJoin("pn", "oa1.parent_department_id", "oa1.effective_date") + `
 		WHERE oa1.department_id = $1 AND oa1.effective_date = $2`
 	
 	var a OrganizationAttribute
 	err := r.db.QueryRow(query, departmentID, effectiveDate).Scan(
-		&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, 
-		&a.ExpirationDate, &a.CreatedAt, &a.UpdatedAt)
+		&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, &a.Abolished,
+		&a.ExpirationDate, &a.DepartmentName, &a.ParentDepartmentName, &a.CreatedAt, &a.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -107,6 +144,7 @@ func (r *OrganizationAttributeRepository) GetDepartmentHistory(departmentID stri
 				oa1.department_id,
 				oa1.effective_date,
 				oa1.parent_department_id,
+				oa1.is_abolished,
 				oa1.created_at,
 				oa1.updated_at,
 				(
@@ -119,14 +157,19 @@ func (r *OrganizationAttributeRepository) GetDepartmentHistory(departmentID stri
 			WHERE oa1.department_id = $1
 		)
 		SELECT 
-			department_id,
-			effective_date,
-			parent_department_id,
-			expiration_date,
-			created_at,
-			updated_at
-		FROM org_attrs
-		ORDER BY effective_date DESC`
+			oa.department_id,
+			oa.effective_date,
+			oa.parent_department_id,
+			oa.is_abolished,
+			oa.expiration_date,
+			n.department_name,
+			pn.department_name,
+			oa.created_at,
+			oa.updated_at
+		FROM org_attrs oa
+		` + nameAtJoin("n", "oa.department_id", "oa.effective_date") + `
+		` + nameAtJoin("pn", "oa.parent_department_id", "oa.effective_date") + `
+		ORDER BY oa.effective_date DESC`
 	
 	rows, err := r.db.Query(query, departmentID)
 	if err != nil {
@@ -137,8 +180,8 @@ func (r *OrganizationAttributeRepository) GetDepartmentHistory(departmentID stri
 	var attrs []OrganizationAttribute
 	for rows.Next() {
 		var a OrganizationAttribute
-		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, 
-			&a.ExpirationDate, &a.CreatedAt, &a.UpdatedAt)
+		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, &a.Abolished,
+			&a.ExpirationDate, &a.DepartmentName, &a.ParentDepartmentName, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -149,79 +192,112 @@ func (r *OrganizationAttributeRepository) GetDepartmentHistory(departmentID stri
 }
 
 func (r *OrganizationAttributeRepository) Create(a *OrganizationAttribute) error {
-	query := `INSERT INTO organization_attributes (department_id, effective_date, parent_department_id) 
-			  VALUES ($1, $2, $3) 
+	query := `INSERT INTO organization_attributes (department_id, effective_date, parent_department_id, is_abolished) 
+			  VALUES ($1, $2, $3, $4) 
 			  RETURNING created_at, updated_at`
 	
-	err := r.db.QueryRow(query, a.DepartmentID, a.EffectiveDate, a.ParentDepartmentID).
-		Scan(&a.CreatedAt, &a.UpdatedAt)
-	return err
+	if err := validateAbolitionRecord(a); err != nil {
+		return err
+	}
+	
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, a.DepartmentID, a.EffectiveDate, a.ParentDepartmentID, a.Abolished).
+			Scan(&a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := validateDepartmentChange(tx, a.DepartmentID, a.EffectiveDate); err != nil {
+			return err
+		}
+		return refreshClosure(tx, a.DepartmentID)
+	})
 }
 
 func (r *OrganizationAttributeRepository) Update(a *OrganizationAttribute) error {
 	query := `UPDATE organization_attributes 
-			  SET parent_department_id = $3, updated_at = CURRENT_TIMESTAMP 
+			  SET parent_department_id = $3, is_abolished = $4, updated_at = CURRENT_TIMESTAMP 
 			  WHERE department_id = $1 AND effective_date = $2 
 			  RETURNING updated_at`
 	
-	err := r.db.QueryRow(query, a.DepartmentID, a.EffectiveDate, a.ParentDepartmentID).
-		Scan(&a.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return nil
+	if err := validateAbolitionRecord(a); err != nil {
+		return err
 	}
-	return err
+	
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, a.DepartmentID, a.EffectiveDate, a.ParentDepartmentID, a.Abolished).
+			Scan(&a.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateDepartmentChange(tx, a.DepartmentID, a.EffectiveDate); err != nil {
+			return err
+		}
+		return refreshClosure(tx, a.DepartmentID)
+	})
 }
 
 func (r *OrganizationAttributeRepository) Delete(departmentID string, effectiveDate time.Time) error {
 	query := `DELETE FROM organization_attributes WHERE department_id = $1 AND effective_date = $2`
 	
-	result, err := r.db.Exec(query, departmentID, effectiveDate)
-	if err != nil {
-		return err
-	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-	
-	return nil
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, departmentID, effectiveDate)
+		if err != nil {
+			return err
+		}
+		
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+		
+		// 削除により前のレコードの有効期間が延びるため、その期間を検証する
+		if err := validateDepartmentChange(tx, departmentID, effectiveDate); err != nil {
+			return err
+		}
+		return refreshClosure(tx, departmentID)
+	})
 }
 
 // GetHierarchyByDate 特定日付時点の組織階層を取得
+// 廃止レコードが発効している部門は含まない
 func (r *OrganizationAttributeRepository) GetHierarchyByDate(targetDate time.Time) ([]OrganizationAttribute, error) {
+	return hierarchyByDate(r.db, targetDate)
+}
+
+// queryer *sql.DB と *sql.Tx の共通インターフェース
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func hierarchyByDate(q queryer, targetDate time.Time) ([]OrganizationAttribute, error) {
+	// 閉包テーブルの自部門の行は廃止レコード以外の組織属性レコードに対応し、valid_to が失効年月日
 	query := `
-		WITH latest_attrs AS (
-			SELECT DISTINCT ON (department_id) 
-				department_id,
-				effective_date,
-				parent_department_id,
-				created_at,
-				updated_at
-			FROM organization_attributes
-			WHERE effective_date <= $1
-			ORDER BY department_id, effective_date DESC
-		)
 		SELECT 
-			la.department_id,
-			la.effective_date,
-			la.parent_department_id,
-			(
-				SELECT MIN(oa2.effective_date) - INTERVAL '1 day'
-				FROM organization_attributes oa2
-				WHERE oa2.department_id = la.department_id
-				AND oa2.effective_date > la.effective_date
-			) AS expiration_date,
-			la.created_at,
-			la.updated_at
-		FROM latest_attrs la
-		ORDER BY la.department_id`
+			c.department_id,
+			c.valid_from,
+			oa.parent_department_id,
+			oa.is_abolished,
+			c.valid_to,
+			n.department_name,
+			pn.department_name,
+			oa.created_at,
+			oa.updated_at
+		FROM organization_closure c
+		JOIN organization_attributes oa ON oa.department_id = c.department_id AND oa.effective_date = c.valid_from
+		` + nameAtJoin("n", "c.department_id", "$1") + `
+		` + nameAtJoin("pn", "oa.parent_department_id", "$1") + `
+		WHERE c.depth = 0 AND ` + closureAt("c", "$1") + `
+		ORDER BY c.department_id`
 	
-	rows, err := r.db.Query(query, targetDate)
+	rows, err := q.Query(query, targetDate)
 	if err != nil {
 		return nil, err
 	}
@@ -230,8 +306,8 @@ func (r *OrganizationAttributeRepository) GetHierarchyByDate(targetDate time.Tim
 	var attrs []OrganizationAttribute
 	for rows.Next() {
 		var a OrganizationAttribute
-		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, 
-			&a.ExpirationDate, &a.CreatedAt, &a.UpdatedAt)
+		err := rows.Scan(&a.DepartmentID, &a.EffectiveDate, &a.ParentDepartmentID, &a.Abolished,
+			&a.ExpirationDate, &a.DepartmentName, &a.ParentDepartmentName, &a.CreatedAt, &a.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -239,4 +315,32 @@ func (r *OrganizationAttributeRepository) GetHierarchyByDate(targetDate time.Tim
 	}
 
 	return attrs, nil
-}
\ No newline at end of file
+}
+
+// GetHierarchyTree 特定日付時点の組織階層をツリー形式で取得
+// knownAt を指定した場合はその日時にシステムに登録されていた内容による
+// rootID の部門がその日付時点に存在しない場合は nil を返す
+func (r *OrganizationAttributeRepository) GetHierarchyTree(targetDate time.Time, knownAt *time.Time, rootID string, maxDepth int) ([]*HierarchyNode, error) {
+	attrs, err := r.hierarchy(targetDate, knownAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildHierarchyTree(attrs, rootID, maxDepth), nil
+}
+
+// GetHierarchyDiff 2時点間の組織階層の差分を取得
+// knownAt を指定した場合はその日時にシステムに登録されていた内容による
+func (r *OrganizationAttributeRepository) GetHierarchyDiff(from, to time.Time, knownAt *time.Time) (*HierarchyDiff, error) {
+	fromAttrs, err := r.hierarchy(from, knownAt)
+	if err != nil {
+		return nil, err
+	}
+
+	toAttrs, err := r.hierarchy(to, knownAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffHierarchies(from, to, fromAttrs, toAttrs), nil
+}
@@ -0,0 +1,535 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	PlanStatusDraft     = "draft"
+	PlanStatusApplied   = "applied"
+	PlanStatusCancelled = "cancelled"
+
+	ChangeActionCreate  = "create"
+	ChangeActionMove    = "move"
+	ChangeActionAbolish = "abolish"
+)
+
+// ReorganizationChange 組織改編計画に含まれる1件の変更
+type ReorganizationChange struct {
+	Action             string  `json:"action"` // create | move | abolish
+	DepartmentID       string  `json:"department_id"`
+	ParentDepartmentID *string `json:"parent_department_id"`
+	DepartmentName     *string `json:"department_name,omitempty"` // create のみ
+	ShortName          *string `json:"short_name,omitempty"`      // create のみ
+}
+
+// ReorganizationPlan 組織改編計画
+type ReorganizationPlan struct {
+	PlanID        int64                  `json:"plan_id"`
+	Name          string                 `json:"name"`
+	EffectiveDate time.Time              `json:"effective_date"`
+	Status        string                 `json:"status"`
+	Changes       []ReorganizationChange `json:"changes"`
+	AppliedAt     *time.Time             `json:"applied_at"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// PlanIssue 組織改編計画の検証で見つかった問題
+type PlanIssue struct {
+	Index        int    `json:"index"` // changes 内の位置（計画全体に関する問題は -1）
+	DepartmentID string `json:"department_id,omitempty"`
+	Message      string `json:"message"`
+}
+
+// PlanPreview 組織改編計画を適用した結果のプレビュー
+type PlanPreview struct {
+	PlanID int64            `json:"plan_id"`
+	Date   string           `json:"date"`
+	Valid  bool             `json:"valid"`
+	Issues []PlanIssue      `json:"issues"`
+	Tree   []*HierarchyNode `json:"tree"`
+	Diff   *HierarchyDiff   `json:"diff"`
+}
+
+// PlanValidationError 組織改編計画の適用時に検証エラーがあった場合のエラー
+type PlanValidationError struct {
+	Issues []PlanIssue
+}
+
+func (e *PlanValidationError) Error() string {
+	return fmt.Sprintf("Reorganization plan has %d validation issues", len(e.Issues))
+}
+
+// PlanStateError 計画の状態により操作できない場合のエラー
+type PlanStateError struct {
+	Status string
+}
+
+func (e *PlanStateError) Error() string {
+	return fmt.Sprintf("Reorganization plan is %s; only draft plans can be changed", e.Status)
+}
+
+type ReorganizationPlanRepository struct {
+	db *sql.DB
+}
+
+func NewReorganizationPlanRepository(db *sql.DB) *ReorganizationPlanRepository {
+	return &ReorganizationPlanRepository{db: db}
+}
+
+func (r *ReorganizationPlanRepository) GetAll() ([]ReorganizationPlan, error) {
+	rows, err := r.db.Query(`SELECT plan_id FROM reorganization_plans ORDER BY effective_date DESC, plan_id DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	plans := []ReorganizationPlan{}
+	for _, id := range ids {
+		plan, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			plans = append(plans, *plan)
+		}
+	}
+
+	return plans, nil
+}
+
+func (r *ReorganizationPlanRepository) GetByID(planID int64) (*ReorganizationPlan, error) {
+	return getPlan(r.db, planID, false)
+}
+
+// Create 下書き状態の計画を登録する
+func (r *ReorganizationPlanRepository) Create(p *ReorganizationPlan) error {
+	if issues := validatePlanContent(p); len(issues) > 0 {
+		return &PlanValidationError{Issues: issues}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO reorganization_plans (name, effective_date, status)
+			  VALUES ($1, $2, $3)
+			  RETURNING plan_id, status, created_at, updated_at`
+
+	err = tx.QueryRow(query, p.Name, p.EffectiveDate, PlanStatusDraft).
+		Scan(&p.PlanID, &p.Status, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPlanChanges(tx, p.PlanID, p.Changes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update 下書き状態の計画の内容を置き換える
+func (r *ReorganizationPlanRepository) Update(p *ReorganizationPlan) error {
+	if issues := validatePlanContent(p); len(issues) > 0 {
+		return &PlanValidationError{Issues: issues}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := getPlan(tx, p.PlanID, true)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return sql.ErrNoRows
+	}
+	if current.Status != PlanStatusDraft {
+		return &PlanStateError{Status: current.Status}
+	}
+
+	query := `UPDATE reorganization_plans
+			  SET name = $2, effective_date = $3, updated_at = CURRENT_TIMESTAMP
+			  WHERE plan_id = $1
+			  RETURNING status, created_at, updated_at`
+
+	err = tx.QueryRow(query, p.PlanID, p.Name, p.EffectiveDate).Scan(&p.Status, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM reorganization_plan_changes WHERE plan_id = $1`, p.PlanID); err != nil {
+		return err
+	}
+	if err := insertPlanChanges(tx, p.PlanID, p.Changes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel 下書き状態の計画を取り消す
+func (r *ReorganizationPlanRepository) Cancel(planID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := getPlan(tx, planID, true)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return sql.ErrNoRows
+	}
+	if current.Status != PlanStatusDraft {
+		return &PlanStateError{Status: current.Status}
+	}
+
+	_, err = tx.Exec(`UPDATE reorganization_plans SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE plan_id = $1`,
+		planID, PlanStatusCancelled)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Preview 下書き状態の計画を適用した場合の組織階層と検証結果を返す
+// 変更はトランザクション内で反映した後にロールバックするため、データベースは変更されない
+// 計画が存在しない場合は nil を返す
+func (r *ReorganizationPlanRepository) Preview(planID int64) (*PlanPreview, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	plan, err := getPlan(tx, planID, false)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	if plan.Status != PlanStatusDraft {
+		return nil, &PlanStateError{Status: plan.Status}
+	}
+
+	before, err := hierarchyByDate(tx, plan.EffectiveDate)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := applyPlanChanges(tx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := hierarchyByDate(tx, plan.EffectiveDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// 差分の比較元は計画を反映しない場合の発効日時点の組織だが、改編前として発効日の前日の日付で表す
+	return &PlanPreview{
+		PlanID: plan.PlanID,
+		Date:   plan.EffectiveDate.Format("2006-01-02"),
+		Valid:  len(issues) == 0,
+		Issues: issues,
+		Tree:   BuildHierarchyTree(after, "", -1),
+		Diff:   DiffHierarchies(plan.EffectiveDate.AddDate(0, 0, -1), plan.EffectiveDate, before, after),
+	}, nil
+}
+
+// Apply 計画のすべての変更を1トランザクションで適用する
+// 検証エラーがある場合は何も変更せず PlanValidationError を返す
+func (r *ReorganizationPlanRepository) Apply(planID int64) (*ReorganizationPlan, error) {
+	var applied *ReorganizationPlan
+
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		plan, err := getPlan(tx, planID, true)
+		if err != nil {
+			return err
+		}
+		if plan == nil {
+			return sql.ErrNoRows
+		}
+		if plan.Status != PlanStatusDraft {
+			return &PlanStateError{Status: plan.Status}
+		}
+
+		issues, err := applyPlanChanges(tx, plan)
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			return &PlanValidationError{Issues: issues}
+		}
+
+		query := `UPDATE reorganization_plans
+				  SET status = $2, applied_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+				  WHERE plan_id = $1
+				  RETURNING status, applied_at, updated_at`
+		err = tx.QueryRow(query, planID, PlanStatusApplied).Scan(&plan.Status, &plan.AppliedAt, &plan.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		applied = plan
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// validatePlanContent データベースを参照せずに確認できる計画の内容を検証する
+func validatePlanContent(p *ReorganizationPlan) []PlanIssue {
+	var issues []PlanIssue
+	if p.Name == "" {
+		issues = append(issues, PlanIssue{Index: -1, Message: "Plan name is required"})
+	}
+	if len(p.Changes) == 0 {
+		issues = append(issues, PlanIssue{Index: -1, Message: "Plan must contain at least one change"})
+	}
+
+	seen := map[string]int{}
+	for i, change := range p.Changes {
+		add := func(message string) {
+			issues = append(issues, PlanIssue{Index: i, DepartmentID: change.DepartmentID, Message: message})
+		}
+
+		if change.DepartmentID == "" {
+			add("Department ID is required")
+			continue
+		}
+		if first, ok := seen[change.DepartmentID]; ok {
+			add(fmt.Sprintf("Department %s is already changed by change %d", change.DepartmentID, first))
+		} else {
+			seen[change.DepartmentID] = i
+		}
+
+		switch change.Action {
+		case ChangeActionCreate:
+			if change.DepartmentName == nil || *change.DepartmentName == "" {
+				add("Department name is required to create a department")
+			}
+		case ChangeActionMove:
+		case ChangeActionAbolish:
+			if change.ParentDepartmentID != nil {
+				add("Abolition cannot have a parent department")
+			}
+		default:
+			add("Action must be one of create, move or abolish")
+		}
+		if change.Action != ChangeActionCreate && (change.DepartmentName != nil || change.ShortName != nil) {
+			add("Department name can only be given to create a department")
+		}
+	}
+
+	return issues
+}
+
+// applyPlanChanges トランザクション内で計画の変更を反映し、検証で見つかった問題を返す
+// 変更の途中では一時的に循環などが生じうるため、検証はすべての変更を反映した後に行う
+func applyPlanChanges(tx *sql.Tx, plan *ReorganizationPlan) ([]PlanIssue, error) {
+	issues := validatePlanContent(plan)
+	if len(issues) > 0 {
+		return issues, nil
+	}
+	issues = []PlanIssue{}
+
+	// 新設部門は先にすべて登録し、計画内の順序によらず同じ計画の中で上位部門として参照できるようにする
+	failed := map[int]bool{}
+	for i, change := range plan.Changes {
+		if change.Action != ChangeActionCreate {
+			continue
+		}
+		message, err := createPlanDepartment(tx, plan.EffectiveDate, change)
+		if err != nil {
+			return nil, err
+		}
+		if message != "" {
+			issues = append(issues, PlanIssue{Index: i, DepartmentID: change.DepartmentID, Message: message})
+			failed[i] = true
+		}
+	}
+
+	var applied []int
+	for i, change := range plan.Changes {
+		if failed[i] {
+			continue
+		}
+		message, err := applyPlanChange(tx, plan.EffectiveDate, change)
+		if err != nil {
+			return nil, err
+		}
+		if message != "" {
+			issues = append(issues, PlanIssue{Index: i, DepartmentID: change.DepartmentID, Message: message})
+			continue
+		}
+		applied = append(applied, i)
+	}
+
+	for _, i := range applied {
+		change := plan.Changes[i]
+		err := validateDepartmentChange(tx, change.DepartmentID, plan.EffectiveDate)
+		if IsValidationError(err) {
+			issues = append(issues, PlanIssue{Index: i, DepartmentID: change.DepartmentID, Message: err.Error()})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// プレビューでも反映後の階層を閉包テーブルから読めるよう、検証結果にかかわらず更新する
+	changed := make([]string, 0, len(applied))
+	for _, i := range applied {
+		changed = append(changed, plan.Changes[i].DepartmentID)
+	}
+	if err := refreshClosure(tx, changed...); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(issues, func(a, b int) bool {
+		return issues[a].Index < issues[b].Index
+	})
+	return issues, nil
+}
+
+// createPlanDepartment 新設の変更の部門と部門名称を登録する。登録できない場合はその理由を返す
+func createPlanDepartment(tx *sql.Tx, date time.Time, change ReorganizationChange) (string, error) {
+	exists, err := departmentExists(tx, change.DepartmentID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return fmt.Sprintf("Department %s already exists; use move instead", change.DepartmentID), nil
+	}
+
+	if _, err := tx.Exec(`INSERT INTO departments (department_id) VALUES ($1)`, change.DepartmentID); err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`INSERT INTO department_names (department_id, effective_date, department_name, short_name)
+					  VALUES ($1, $2, $3, $4)`, change.DepartmentID, date, *change.DepartmentName, change.ShortName)
+	return "", err
+}
+
+// applyPlanChange 1件の変更の組織属性を反映する（新設部門は createPlanDepartment で登録済み）。反映できない場合はその理由を返す
+func applyPlanChange(tx *sql.Tx, date time.Time, change ReorganizationChange) (string, error) {
+	if change.Action != ChangeActionCreate {
+		exists, err := departmentExists(tx, change.DepartmentID)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return fmt.Sprintf("Department %s does not exist", change.DepartmentID), nil
+		}
+	}
+
+	if change.ParentDepartmentID != nil {
+		parentExists, err := departmentExists(tx, *change.ParentDepartmentID)
+		if err != nil {
+			return "", err
+		}
+		if !parentExists {
+			return fmt.Sprintf("Parent department %s does not exist", *change.ParentDepartmentID), nil
+		}
+	}
+
+	// 発効日に既にレコードがある場合は計画の内容で置き換える
+	_, err := tx.Exec(`INSERT INTO organization_attributes (department_id, effective_date, parent_department_id, is_abolished)
+					  VALUES ($1, $2, $3, $4)
+					  ON CONFLICT (department_id, effective_date)
+					  DO UPDATE SET parent_department_id = EXCLUDED.parent_department_id,
+									is_abolished = EXCLUDED.is_abolished,
+									updated_at = CURRENT_TIMESTAMP`,
+		change.DepartmentID, date, change.ParentDepartmentID, change.Action == ChangeActionAbolish)
+	if err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+func departmentExists(tx *sql.Tx, departmentID string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM departments WHERE department_id = $1)`, departmentID).Scan(&exists)
+	return exists, err
+}
+
+// getPlan 計画を取得する。forUpdate の場合は計画の行をロックする
+func getPlan(q queryer, planID int64, forUpdate bool) (*ReorganizationPlan, error) {
+	query := `SELECT plan_id, name, effective_date, status, applied_at, created_at, updated_at
+			  FROM reorganization_plans
+			  WHERE plan_id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var p ReorganizationPlan
+	err := q.QueryRow(query, planID).Scan(&p.PlanID, &p.Name, &p.EffectiveDate, &p.Status,
+		&p.AppliedAt, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`SELECT action, department_id, parent_department_id, department_name, short_name
+						  FROM reorganization_plan_changes
+						  WHERE plan_id = $1
+						  ORDER BY seq`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Changes = []ReorganizationChange{}
+	for rows.Next() {
+		var c ReorganizationChange
+		if err := rows.Scan(&c.Action, &c.DepartmentID, &c.ParentDepartmentID, &c.DepartmentName, &c.ShortName); err != nil {
+			return nil, err
+		}
+		p.Changes = append(p.Changes, c)
+	}
+
+	return &p, rows.Err()
+}
+
+func insertPlanChanges(tx *sql.Tx, planID int64, changes []ReorganizationChange) error {
+	for i, c := range changes {
+		_, err := tx.Exec(`INSERT INTO reorganization_plan_changes
+						   (plan_id, seq, action, department_id, parent_department_id, department_name, short_name)
+						   VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			planID, i, c.Action, c.DepartmentID, c.ParentDepartmentID, c.DepartmentName, c.ShortName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
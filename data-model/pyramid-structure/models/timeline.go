@@ -0,0 +1,179 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// NameChange 部門名称の変更（改称）
+type NameChange struct {
+	DepartmentID string  `json:"department_id"`
+	OldName      string  `json:"old_name"`
+	OldShortName *string `json:"old_short_name"`
+	NewName      string  `json:"new_name"`
+	NewShortName *string `json:"new_short_name"`
+}
+
+// TimelineEntry 組織が変化した発効日と、その日の変化の内容
+type TimelineEntry struct {
+	Date      string            `json:"date"`
+	Created   []HierarchyChange `json:"created"`   // 新設（廃止後の再発足を含む）
+	Moved     []HierarchyChange `json:"moved"`     // 上位部門の変更
+	Abolished []HierarchyChange `json:"abolished"` // 廃止
+	Renamed   []NameChange      `json:"renamed"`   // 改称
+}
+
+// TimelineFilter 組織改編の時系列の絞り込み条件（指定しない項目は nil または空文字）
+type TimelineFilter struct {
+	From         *time.Time
+	To           *time.Time
+	DepartmentID string // この部門自身の変化と、この部門を移管前後・新設時・廃止前の上位部門とする変化
+}
+
+// timelineAttributeChanges 組織属性のレコードを同じ部門の直前のレコードと比べた変化
+// 部門名は新設・移管では発効日、廃止と移管前の上位部門では発効日前日の名称を使う
+var timelineAttributeChanges = `
+	WITH records AS (
+		SELECT
+			department_id,
+			effective_date,
+			parent_department_id,
+			is_abolished,
+			LAG(effective_date) OVER w AS prev_date,
+			LAG(parent_department_id) OVER w AS prev_parent_id,
+			LAG(is_abolished) OVER w AS prev_abolished
+		FROM organization_attributes
+		WINDOW w AS (PARTITION BY department_id ORDER BY effective_date)
+	),
+	changes AS (
+		SELECT
+			r.*,
+			CASE
+				WHEN NOT r.is_abolished AND (r.prev_date IS NULL OR r.prev_abolished) THEN 'created'
+				WHEN r.is_abolished AND r.prev_date IS NOT NULL AND NOT r.prev_abolished THEN 'abolished'
+				WHEN NOT r.is_abolished AND r.parent_department_id IS DISTINCT FROM r.prev_parent_id THEN 'moved'
+			END AS change_type
+		FROM records r
+	)
+	SELECT
+		c.effective_date,
+		c.change_type,
+		c.department_id,
+		COALESCE(n.department_name, c.department_id),
+		CASE WHEN c.change_type <> 'created' THEN c.prev_parent_id END,
+		CASE WHEN c.change_type <> 'created' THEN opn.department_name END,
+		CASE WHEN c.change_type <> 'abolished' THEN c.parent_department_id END,
+		CASE WHEN c.change_type <> 'abolished' THEN npn.department_name END
+	FROM changes c
+	` + nameAtJoin("n", "c.department_id",
+	"CASE WHEN c.change_type = 'abolished' THEN c.effective_date - 1 ELSE c.effective_date END") + `
+	` + nameAtJoin("opn", "c.prev_parent_id", "c.effective_date - 1") + `
+	` + nameAtJoin("npn", "c.parent_department_id", "c.effective_date") + `
+	WHERE c.change_type IS NOT NULL
+	AND ($1::date IS NULL OR c.effective_date >= $1::date)
+	AND ($2::date IS NULL OR c.effective_date <= $2::date)
+	AND ($3::text = '' OR c.department_id = $3::text
+		OR (c.change_type <> 'created' AND c.prev_parent_id = $3::text)
+		OR (c.change_type <> 'abolished' AND c.parent_department_id = $3::text))
+	ORDER BY c.effective_date, c.department_id`
+
+// timelineNameChanges 部門名称のレコードを同じ部門の直前のレコードと比べた変化（最初の名称は新設として扱うため含めない）
+const timelineNameChanges = `
+	WITH records AS (
+		SELECT
+			department_id,
+			effective_date,
+			department_name,
+			short_name,
+			LAG(effective_date) OVER w AS prev_date,
+			LAG(department_name) OVER w AS prev_name,
+			LAG(short_name) OVER w AS prev_short_name
+		FROM department_names
+		WINDOW w AS (PARTITION BY department_id ORDER BY effective_date)
+	)
+	SELECT effective_date, department_id, prev_name, prev_short_name, department_name, short_name
+	FROM records
+	WHERE prev_date IS NOT NULL
+	AND (department_name <> prev_name OR short_name IS DISTINCT FROM prev_short_name)
+	AND ($1::date IS NULL OR effective_date >= $1::date)
+	AND ($2::date IS NULL OR effective_date <= $2::date)
+	AND ($3::text = '' OR department_id = $3::text)
+	ORDER BY effective_date, department_id`
+
+// GetTimeline 組織が変化した発効日を古い順に、その日の新設・移管・廃止・改称とともに取得
+// 組織属性・部門名称のレコードがあっても、直前のレコードと内容が同じ（変化がない）日付は含めない
+func (r *OrganizationAttributeRepository) GetTimeline(filter TimelineFilter) ([]TimelineEntry, error) {
+	entries := []TimelineEntry{}
+	index := map[string]int{}
+	entry := func(date time.Time) *TimelineEntry {
+		key := date.Format("2006-01-02")
+		i, ok := index[key]
+		if !ok {
+			i = len(entries)
+			index[key] = i
+			entries = append(entries, TimelineEntry{
+				Date:      key,
+				Created:   []HierarchyChange{},
+				Moved:     []HierarchyChange{},
+				Abolished: []HierarchyChange{},
+				Renamed:   []NameChange{},
+			})
+		}
+		return &entries[i]
+	}
+
+	rows, err := r.db.Query(timelineAttributeChanges, filter.From, filter.To, filter.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var date time.Time
+		var changeType string
+		var c HierarchyChange
+		err := rows.Scan(&date, &changeType, &c.DepartmentID, &c.DepartmentName,
+			&c.OldParentDepartmentID, &c.OldParentDepartmentName, &c.NewParentDepartmentID, &c.NewParentDepartmentName)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		e := entry(date)
+		switch changeType {
+		case "created":
+			e.Created = append(e.Created, c)
+		case "moved":
+			e.Moved = append(e.Moved, c)
+		case "abolished":
+			e.Abolished = append(e.Abolished, c)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(timelineNameChanges, filter.From, filter.To, filter.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var date time.Time
+		var c NameChange
+		if err := rows.Scan(&date, &c.DepartmentID, &c.OldName, &c.OldShortName, &c.NewName, &c.NewShortName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e := entry(date)
+		e.Renamed = append(e.Renamed, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 改称のみの日付は組織属性の変化の後に追加されるため、日付順に並べ直す
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].Date < entries[b].Date
+	})
+	return entries, nil
+}
@@ -0,0 +1,366 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CycleError 組織属性の変更により上位部門の参照が循環する場合のエラー
+type CycleError struct {
+	Date time.Time
+	Path []string // 循環の起点から起点に戻るまでの部門ID
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("Organization hierarchy would contain a cycle on %s: %s",
+		e.Date.Format("2006-01-02"), strings.Join(e.Path, " → "))
+}
+
+// ActiveChildrenError 配下に存続する部門がある部門を廃止しようとした場合のエラー
+type ActiveChildrenError struct {
+	DepartmentID string
+	Date         time.Time
+	Children     []string
+}
+
+func (e *ActiveChildrenError) Error() string {
+	return fmt.Sprintf("Department %s cannot be abolished on %s while it has active child departments: %s",
+		e.DepartmentID, e.Date.Format("2006-01-02"), strings.Join(e.Children, ", "))
+}
+
+// ParentNotActiveError 上位部門がその日付時点で組織に存在しない（未発足・廃止済み）場合のエラー
+type ParentNotActiveError struct {
+	DepartmentID       string
+	ParentDepartmentID string
+	Date               time.Time
+}
+
+func (e *ParentNotActiveError) Error() string {
+	return fmt.Sprintf("Parent department %s of %s is not part of the organization on %s",
+		e.ParentDepartmentID, e.DepartmentID, e.Date.Format("2006-01-02"))
+}
+
+// OrphanedChildrenError 配下の部門が上位部門として参照している期間に、部門が組織に存在しなくなる場合のエラー
+type OrphanedChildrenError struct {
+	DepartmentID string
+	Date         time.Time
+	Children     []string
+}
+
+func (e *OrphanedChildrenError) Error() string {
+	return fmt.Sprintf("Department %s would not be part of the organization on %s while child departments still reference it: %s",
+		e.DepartmentID, e.Date.Format("2006-01-02"), strings.Join(e.Children, ", "))
+}
+
+// StaffedDepartmentError 部門長・所属の期間中に、部門が組織に存在しなくなる場合のエラー
+type StaffedDepartmentError struct {
+	DepartmentID string
+	Date         time.Time // 部門長・所属の期間のうち、部門が組織に存在しない最初の日付
+	Heads        []string  // 部門長の社員ID
+	Members      []string  // 所属する社員ID
+}
+
+func (e *StaffedDepartmentError) Error() string {
+	var staff []string
+	if len(e.Heads) > 0 {
+		staff = append(staff, "heads "+strings.Join(e.Heads, ", "))
+	}
+	if len(e.Members) > 0 {
+		staff = append(staff, "assigned employees "+strings.Join(e.Members, ", "))
+	}
+	return fmt.Sprintf("Department %s would not be part of the organization on %s while it still has %s",
+		e.DepartmentID, e.Date.Format("2006-01-02"), strings.Join(staff, " and "))
+}
+
+// ValidationError その他の入力内容の検証エラー
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// IsValidationError 組織構造の整合性検証に違反したエラーかどうかを返す
+func IsValidationError(err error) bool {
+	var cycleErr *CycleError
+	var childrenErr *ActiveChildrenError
+	var parentErr *ParentNotActiveError
+	var orphanedErr *OrphanedChildrenError
+	var staffedErr *StaffedDepartmentError
+	var validationErr *ValidationError
+	return errors.As(err, &cycleErr) || errors.As(err, &childrenErr) || errors.As(err, &parentErr) ||
+		errors.As(err, &orphanedErr) || errors.As(err, &staffedErr) || errors.As(err, &validationErr)
+}
+
+// validateAbolitionRecord 廃止レコードは上位部門を持たない
+func validateAbolitionRecord(a *OrganizationAttribute) error {
+	if a.Abolished && a.ParentDepartmentID != nil {
+		return &ValidationError{Message: "Abolition records cannot have a parent department"}
+	}
+	return nil
+}
+
+// withTx 組織属性を変更するトランザクションを実行する
+// 同時に実行された変更が組み合わさって不整合にならないよう、組織属性テーブルへの書き込みを直列化する
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`LOCK TABLE organization_attributes IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// validateDepartmentChange 部門の組織属性が from 以降で変わったときの整合性を検証する
+// 影響を受けるのは from から同じ部門の次のレコードの発効日前日までの期間
+func validateDepartmentChange(tx *sql.Tx, departmentID string, from time.Time) error {
+	dates, err := checkDates(tx, departmentID, from)
+	if err != nil {
+		return err
+	}
+
+	for _, date := range dates {
+		if err := checkCycle(tx, departmentID, date); err != nil {
+			return err
+		}
+		if err := checkParentActive(tx, departmentID, date); err != nil {
+			return err
+		}
+		if err := checkActiveChildren(tx, departmentID, date); err != nil {
+			return err
+		}
+	}
+
+	staffed, err := checkStaffedDepartments(tx, departmentID, &from)
+	if err != nil {
+		return err
+	}
+	if len(staffed) > 0 {
+		return staffed[0]
+	}
+
+	return nil
+}
+
+// checkDates from と、影響期間中に他部門のレコードが発効する日付を返す
+func checkDates(tx *sql.Tx, departmentID string, from time.Time) ([]time.Time, error) {
+	query := `
+		SELECT $2::date
+		UNION
+		SELECT DISTINCT effective_date
+		FROM organization_attributes
+		WHERE effective_date > $2
+		AND effective_date < COALESCE((
+			SELECT MIN(effective_date)
+			FROM organization_attributes
+			WHERE department_id = $1 AND effective_date > $2
+		), 'infinity'::date)
+		ORDER BY 1`
+
+	rows, err := tx.Query(query, departmentID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []time.Time
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		dates = append(dates, d)
+	}
+
+	return dates, rows.Err()
+}
+
+// asOfAttributesAt dateExpr の日付時点で発効している各部門のレコード
+func asOfAttributesAt(dateExpr string) string {
+	return `
+	SELECT DISTINCT ON (department_id)
+		department_id,
+		parent_department_id,
+		is_abolished
+	FROM organization_attributes
+	WHERE effective_date <= ` + dateExpr + `
+	ORDER BY department_id, effective_date DESC`
+}
+
+// asOfAttributes $2 の日付時点で発効している各部門のレコード
+var asOfAttributes = asOfAttributesAt("$2")
+
+// checkCycle 指定日付時点で部門から上位部門をたどり、自部門に戻る経路があれば CycleError を返す
+// 変更されたのは departmentID の上位部門だけなので、新たな循環は必ず departmentID を含む
+func checkCycle(tx *sql.Tx, departmentID string, date time.Time) error {
+	query := `
+		WITH RECURSIVE as_of AS (` + asOfAttributes + `),
+		walk AS (
+			SELECT a.parent_department_id::text AS department_id, ARRAY[a.department_id::text] AS path
+			FROM as_of a
+			WHERE a.department_id = $1::text
+			UNION ALL
+			SELECT a.parent_department_id::text, w.path || w.department_id
+			FROM walk w
+			JOIN as_of a ON a.department_id = w.department_id
+			WHERE w.department_id <> $1::text AND NOT w.department_id = ANY(w.path)
+		)
+		SELECT path || department_id
+		FROM walk
+		WHERE department_id = $1::text
+		LIMIT 1`
+
+	var path []string
+	err := tx.QueryRow(query, departmentID, date).Scan(pq.Array(&path))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &CycleError{Date: date, Path: path}
+}
+
+// checkParentActive 指定日付時点で部門が存続している場合、その上位部門も組織に存在することを確認する
+func checkParentActive(tx *sql.Tx, departmentID string, date time.Time) error {
+	query := `
+		WITH as_of AS (` + asOfAttributes + `)
+		SELECT child.parent_department_id
+		FROM as_of child
+		LEFT JOIN as_of parent ON parent.department_id = child.parent_department_id
+		WHERE child.department_id = $1
+		AND NOT child.is_abolished
+		AND child.parent_department_id IS NOT NULL
+		AND (parent.department_id IS NULL OR parent.is_abolished)`
+
+	var parentID string
+	err := tx.QueryRow(query, departmentID, date).Scan(&parentID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &ParentNotActiveError{DepartmentID: departmentID, ParentDepartmentID: parentID, Date: date}
+}
+
+// checkActiveChildren 指定日付時点で部門が組織に存在しない場合、上位部門として参照している存続部門がないことを確認する
+// 廃止されている場合は ActiveChildrenError、まだ発足していない（レコードの削除で発足日が遅くなった）場合は OrphanedChildrenError を返す
+func checkActiveChildren(tx *sql.Tx, departmentID string, date time.Time) error {
+	query := `
+		WITH as_of AS (` + asOfAttributes + `)
+		SELECT child.department_id, parent.department_id IS NOT NULL
+		FROM as_of child
+		LEFT JOIN as_of parent ON parent.department_id = child.parent_department_id
+		WHERE child.parent_department_id = $1
+		AND (parent.department_id IS NULL OR parent.is_abolished)
+		AND NOT child.is_abolished
+		ORDER BY child.department_id`
+
+	rows, err := tx.Query(query, departmentID, date)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var children []string
+	abolished := false
+	for rows.Next() {
+		var child string
+		if err := rows.Scan(&child, &abolished); err != nil {
+			return err
+		}
+		children = append(children, child)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+	if abolished {
+		return &ActiveChildrenError{DepartmentID: departmentID, Date: date, Children: children}
+	}
+	return &OrphanedChildrenError{DepartmentID: departmentID, Date: date, Children: children}
+}
+
+// checkStaffedDepartments 部門長・所属の期間中に部門が組織に存在しない日があれば、部門ごとに StaffedDepartmentError を返す
+// departmentID を指定した場合はその部門の from から次のレコードの発効日前日まで、空文字の場合はすべての部門の全期間を調べる
+func checkStaffedDepartments(tx *sql.Tx, departmentID string, from *time.Time) ([]*StaffedDepartmentError, error) {
+	query := `
+		WITH inactive AS (` + inactivePeriods + `),
+		scope AS (
+			SELECT
+				COALESCE($2::date, '-infinity'::date) AS scope_from,
+				CASE WHEN $1::text = '' THEN 'infinity'::date ELSE COALESCE((
+					SELECT MIN(effective_date)
+					FROM organization_attributes
+					WHERE department_id = $1::text AND effective_date > $2::date
+				), 'infinity'::date) END AS scope_to
+		),
+		staff AS (
+			SELECT department_id, employee_id, TRUE AS is_head, effective_date,
+				   COALESCE(expiration_date, 'infinity'::date) AS expiration_date
+			FROM department_heads
+			UNION ALL
+			SELECT department_id, employee_id, FALSE, effective_date, COALESCE(expiration_date, 'infinity'::date)
+			FROM employee_assignments
+		)
+		SELECT s.department_id, s.employee_id, s.is_head, MIN(GREATEST(s.effective_date, i.valid_from, scope.scope_from))
+		FROM staff s
+		JOIN inactive i ON i.department_id = s.department_id
+		CROSS JOIN scope
+		WHERE ($1::text = '' OR s.department_id = $1::text)
+		AND GREATEST(s.effective_date, i.valid_from, scope.scope_from) < LEAST(i.valid_to, scope.scope_to)
+		AND GREATEST(s.effective_date, i.valid_from, scope.scope_from) <= s.expiration_date
+		GROUP BY s.department_id, s.employee_id, s.is_head
+		ORDER BY s.department_id, s.employee_id`
+
+	rows, err := tx.Query(query, departmentID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var errs []*StaffedDepartmentError
+	for rows.Next() {
+		var id, employeeID string
+		var head bool
+		var date time.Time
+		if err := rows.Scan(&id, &employeeID, &head, &date); err != nil {
+			return nil, err
+		}
+		if len(errs) == 0 || errs[len(errs)-1].DepartmentID != id {
+			errs = append(errs, &StaffedDepartmentError{DepartmentID: id, Date: date})
+		}
+		e := errs[len(errs)-1]
+		if date.Before(e.Date) {
+			e.Date = date
+		}
+		if head {
+			e.Heads = append(e.Heads, employeeID)
+		} else {
+			e.Members = append(e.Members, employeeID)
+		}
+	}
+
+	return errs, rows.Err()
+}
@@ -41,6 +41,7 @@ function showTab(tabName) {
     // 組織階層タブが選択されたら階層データを読み込む
     if (tabName === 'hierarchy') {
         updateHierarchy();
+        loadTimeline();
     } else if (tabName === 'reorganize') {
         updateReorganizeView();
         updateMoveSelects();
@@ -182,11 +183,11 @@ function renderAttributes(filteredAttrs = null) {
         return `
             <tr>
                 <td>${attr.department_id}</td>
-                <td>${dept ? dept.department_name : ''}</td>
+                <td>${attr.department_name || (dept ? dept.department_name : '')}</td>
                 <td>${formatDate(attr.effective_date)}</td>
                 <td>${attr.expiration_date ? formatDate(attr.expiration_date) : '現在'}</td>
-                <td>${attr.parent_department_id || ''}</td>
-                <td>${parentDept ? parentDept.department_name : ''}</td>
+                <td>${attr.abolished ? '（廃止）' : (attr.parent_department_id || '')}</td>
+                <td>${attr.parent_department_name || (parentDept ? parentDept.department_name : '')}</td>
                 <td>
                     <button class="edit" onclick="editAttribute('${attr.department_id}', '${formatDate(attr.effective_date)}')">編集</button>
                     <button class="delete" onclick="deleteAttribute('${attr.department_id}', '${formatDate(attr.effective_date)}')">削除</button>
@@ -227,6 +228,7 @@ function showAttributeForm() {
     document.getElementById('attrEffectiveDate').disabled = false;
     document.getElementById('attrEffectiveDate').value = '';
     document.getElementById('attrParentDeptId').value = '';
+    document.getElementById('attrAbolished').checked = false;
     document.getElementById('attributeForm').style.display = 'block';
 }
 
@@ -240,12 +242,14 @@ async function saveAttribute(event) {
     const mode = document.getElementById('attrEditMode').value;
     const deptId = document.getElementById('attrDeptId').value;
     const effectiveDate = document.getElementById('attrEffectiveDate').value;
-    const parentDeptId = document.getElementById('attrParentDeptId').value || null;
+    const abolished = document.getElementById('attrAbolished').checked;
+    const parentDeptId = abolished ? null : (document.getElementById('attrParentDeptId').value || null);
     
     const data = {
         department_id: deptId,
         effective_date: effectiveDate,
-        parent_department_id: parentDeptId
+        parent_department_id: parentDeptId,
+        abolished: abolished
     };
     
     try {
@@ -262,7 +266,7 @@ async function saveAttribute(event) {
             response = await fetch(`${API_BASE}/organization-attributes/${originalDeptId}/${originalDate}`, {
                 method: 'PUT',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ parent_department_id: parentDeptId })
+                body: JSON.stringify({ parent_department_id: parentDeptId, abolished: abolished })
             });
         }
         
@@ -292,6 +296,7 @@ async function editAttribute(deptId, effectiveDate) {
     document.getElementById('attrEffectiveDate').value = effectiveDate;
     document.getElementById('attrEffectiveDate').disabled = true;
     document.getElementById('attrParentDeptId').value = attr.parent_department_id || '';
+    document.getElementById('attrAbolished').checked = attr.abolished;
     document.getElementById('attributeForm').style.display = 'block';
 }
 
@@ -336,10 +341,10 @@ async function updateHierarchy() {
     container.innerHTML = '<div class="hierarchy-loading">データを読み込み中...</div>';
     
     try {
-        const response = await fetch(`${API_BASE}/hierarchy?date=${date}`);
+        const response = await fetch(`${API_BASE}/hierarchy/tree?date=${date}`);
         const data = await response.json();
         
-        hierarchyData = buildHierarchyTree(data.attributes);
+        hierarchyData = toViewNodes(data.tree);
         renderHierarchy(hierarchyData, container);
     } catch (error) {
         console.error('階層データの読み込みに失敗しました:', error);
@@ -347,6 +352,20 @@ async function updateHierarchy() {
     }
 }
 
+// サーバーで構築したツリーを表示用のノードに変換
+function toViewNodes(nodes) {
+    return nodes.map(node => ({
+        id: node.department_id,
+        name: node.department_name,
+        effectiveDate: formatDate(node.effective_date),
+        expirationDate: node.expiration_date ? formatDate(node.expiration_date) : null,
+        parentId: node.parent_department_id,
+        fullPath: node.full_path,
+        children: toViewNodes(node.children),
+        expanded: true
+    }));
+}
+
 function buildHierarchyTree(attrs) {
     const nodeMap = new Map();
     const roots = [];
@@ -519,6 +538,71 @@ function exportHierarchy() {
     URL.revokeObjectURL(url);
 }
 
+// 組織改編の時系列（組織が変化した日付をスライダーで選択）
+let timeline = [];
+
+async function loadTimeline() {
+    try {
+        const response = await fetch(`${API_BASE}/timeline`);
+        timeline = await response.json();
+    } catch (error) {
+        console.error('組織改編の時系列の読み込みに失敗しました:', error);
+        timeline = [];
+    }
+    
+    const slider = document.getElementById('timelineSlider');
+    slider.max = Math.max(timeline.length - 1, 0);
+    slider.disabled = timeline.length === 0;
+    
+    // 基準日時点で最後に組織が変化した日付に合わせる
+    const date = document.getElementById('hierarchyDate').value;
+    let index = 0;
+    timeline.forEach((entry, i) => {
+        if (entry.date <= date) {
+            index = i;
+        }
+    });
+    slider.value = index;
+    updateTimelineSummary(index);
+}
+
+function selectTimelineDate() {
+    const index = Number(document.getElementById('timelineSlider').value);
+    const entry = timeline[index];
+    if (!entry) return;
+    
+    document.getElementById('hierarchyDate').value = entry.date;
+    updateTimelineSummary(index);
+    updateHierarchy();
+}
+
+function updateTimelineSummary(index) {
+    const entry = timeline[index];
+    const summary = document.getElementById('timelineSummary');
+    if (!entry) {
+        summary.textContent = '';
+        return;
+    }
+    
+    const counts = [
+        ['新設', entry.created.length],
+        ['移管', entry.moved.length],
+        ['廃止', entry.abolished.length],
+        ['改称', entry.renamed.length]
+    ].filter(([, count]) => count > 0).map(([label, count]) => `${label}${count}件`);
+    summary.textContent = `${entry.date}（${counts.join('・')}）`;
+}
+
+// 組織図のエクスポート（SVG・Mermaid・Graphviz DOT）
+function exportChart(format) {
+    const date = document.getElementById('hierarchyDate').value;
+    const params = new URLSearchParams({ format: format });
+    if (date) {
+        params.set('date', date);
+    }
+    window.open(`${API_BASE}/hierarchy/export?${params}`, '_blank');
+}
+
 // 組織再編機能
 let reorganizeHierarchyData = null;
 